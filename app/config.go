@@ -2,7 +2,14 @@ package app
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
+	"strings"
+)
+
+const (
+	TagDeletePolicyKeep   = "keep"
+	TagDeletePolicyDelete = "delete"
 )
 
 type Config struct {
@@ -12,14 +19,77 @@ type Config struct {
 }
 
 type ServiceConfig struct {
-	WorkDir       string `json:"work_dir"        required:"true"`
-	SyncFileShell string `json:"sync_file_shell" required:"true"`
+	WorkDir string `json:"work_dir" required:"true"`
+
+	// Instance is the identity of this instance which holds the sync lock.
+	// It is the hostname by default.
+	Instance string `json:"instance"`
+
+	// The unit is second. The running lock will be regarded as expired
+	// if it is not renewed within LeaseTimeout.
+	LeaseTimeout int `json:"lease_timeout"`
+
+	// The unit is second
+	HeartbeatInterval int `json:"heartbeat_interval"`
+
+	// Branch is the branch to be synced.
+	// It is the default branch of repo if empty.
+	Branch string `json:"branch"`
+
+	// RepoBranches overrides Branch for the repos.
+	// The key is the repo key, such as owner/repo_id for gitlab and
+	// owner/github-repo_id for github, and the value is the branch.
+	RepoBranches map[string]string `json:"repo_branches"`
+
+	// TagDeletePolicy decides what to do with the snapshot of tag when
+	// the tag is deleted. It is keep or delete and is keep by default.
+	// The deleted snapshot is moved to the trash if the trash is enabled.
+	TagDeletePolicy string `json:"tag_delete_policy"`
 }
 
 type HelperConfig struct {
 	LFSPath    string `json:"lfs_path"    required:"true"`
 	RepoPath   string `json:"repo_path"   required:"true"`
 	CommitFile string `json:"commit_file" required:"true"`
+
+	// TagPath is the directory under the obs path of repo where the
+	// snapshots of tags are saved to, such as
+	// repo_path/user/[project,model,dataset]/repo_id/tag_path/tag.
+	// The files of repo under it are not synced. It is tags by default.
+	TagPath string `json:"tag_path"`
+
+	// TrashPath is where the files of destroyed repo are moved to.
+	// They will be deleted directly if it is empty.
+	TrashPath string `json:"trash_path"`
+
+	// The unit is day. The files in trash will be deleted after it.
+	TrashRetention int `json:"trash_retention"`
+}
+
+func (c *Config) SetDefault() {
+	if c.Instance == "" {
+		c.Instance, _ = os.Hostname()
+	}
+
+	if c.LeaseTimeout <= 0 {
+		c.LeaseTimeout = 600
+	}
+
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = 60
+	}
+
+	if c.TrashRetention <= 0 {
+		c.TrashRetention = 7
+	}
+
+	if c.TagPath == "" {
+		c.TagPath = "tags"
+	}
+
+	if c.TagDeletePolicy == "" {
+		c.TagDeletePolicy = TagDeletePolicyKeep
+	}
 }
 
 func (c *Config) Validate() error {
@@ -27,10 +97,6 @@ func (c *Config) Validate() error {
 		return errors.New("work_dir must be an absolute path")
 	}
 
-	if !filepath.IsAbs(c.SyncFileShell) {
-		return errors.New("sync_file_shell must be an absolute path")
-	}
-
 	if filepath.IsAbs(c.LFSPath) {
 		return errors.New("lfs_path can't start with /")
 	}
@@ -39,5 +105,30 @@ func (c *Config) Validate() error {
 		return errors.New("repo_path can't start with /")
 	}
 
+	if filepath.IsAbs(c.TrashPath) {
+		return errors.New("trash_path can't start with /")
+	}
+
+	if filepath.IsAbs(c.TagPath) {
+		return errors.New("tag_path can't start with /")
+	}
+
+	if v := filepath.Clean(c.TagPath); v == "." || strings.HasPrefix(v, "..") {
+		return errors.New("tag_path must be a directory under the repo")
+	}
+
+	if c.TagDeletePolicy != TagDeletePolicyKeep &&
+		c.TagDeletePolicy != TagDeletePolicyDelete {
+		return errors.New("invalid tag_delete_policy")
+	}
+
+	if c.Instance == "" {
+		return errors.New("missing instance")
+	}
+
+	if c.HeartbeatInterval >= c.LeaseTimeout {
+		return errors.New("heartbeat_interval must be less than lease_timeout")
+	}
+
 	return nil
 }
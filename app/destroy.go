@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+// DestroyRepo purges the files of repo which has been destroyed on
+// the platform, and leaves a tombstone in the lock, so that the late
+// events of repo will be ignored.
+func (s *syncService) DestroyRepo(info *RepoInfo) error {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil {
+		if !synclock.IsRepoSyncLockNotExist(err) {
+			return err
+		}
+
+		c.Owner = info.Owner
+		c.RepoId = info.RepoId
+		c.Platform = info.platformKey()
+	}
+
+	if c.IsDestroyed() {
+		return nil
+	}
+
+	if c.IsRunning() && !c.IsExpired(time.Now().Unix(), int64(s.cfg.LeaseTimeout)) {
+		return errorRepoBusy{errors.New("the repo is being synced")}
+	}
+
+	if c, err = s.lockRepo(&c); err != nil {
+		return err
+	}
+
+	c, ok, err := s.withLock(&c, info, func(ctx context.Context) error {
+		// the snapshots of tags are purged together.
+		return s.purge(ctx, info, s.h.getRepoObsPath(info.repoOBSPath()))
+	})
+	if !ok {
+		return err
+	}
+
+	if err != nil {
+		s.unlockRepo(&c, info)
+
+		return err
+	}
+
+	s.evictMirror(info)
+
+	c.Status = domain.RepoSyncStatusDestroyed
+	c.LastCommit = ""
+	c.Holder = ""
+
+	s.saveLockWithRetry(&c, info)
+
+	return nil
+}
+
+// evictMirror deletes the cached mirror of repo whose key is not used any
+// more. It only costs the disk if failed, so the error is ignored.
+func (s *syncService) evictMirror(info *RepoInfo) {
+	if err := s.engine.EvictMirror(info.RepoKey()); err != nil {
+		s.log.Errorf(
+			"evict the mirror of repo(%s) failed, err:%s",
+			info.repoOBSPath(), err.Error(),
+		)
+	}
+}
+
+// purge deletes the files under the obs path p or moves them to the trash.
+func (s *syncService) purge(ctx context.Context, info *RepoInfo, p string) (err error) {
+	src := p
+	n := 0
+
+	if s.h.cfg.TrashPath == "" {
+		n, err = s.h.deletePrefix(ctx, src)
+		metrics.AddFiles(info.repoType(), metrics.OpDelete, n)
+	} else {
+		dst := filepath.Join(
+			s.h.cfg.TrashPath,
+			strconv.FormatInt(time.Now().Unix(), 10),
+			p,
+		)
+
+		n, err = s.h.movePrefix(ctx, src, dst)
+	}
+
+	if err != nil {
+		return fmt.Errorf("purge %s failed, err:%s", p, err.Error())
+	}
+
+	s.log.Infof("purge %s, %d files", p, n)
+
+	return nil
+}
+
+// CleanTrash deletes the files which have been in the trash longer than
+// the retention. The files of destroyed repo are moved to
+// trash_path/<unix time of purging>/<obs path of files>
+func (s *syncService) CleanTrash() error {
+	trash := s.h.cfg.TrashPath
+	if trash == "" {
+		return nil
+	}
+
+	objs, err := s.h.obsService.ListObjects(trash + "/")
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Now().Unix() - int64(s.h.cfg.TrashRetention)*24*3600
+	n := 0
+
+	for i := range objs {
+		p := objs[i].Path
+
+		v := strings.SplitN(strings.TrimPrefix(p, trash+"/"), "/", 2)
+		t, err := strconv.ParseInt(v[0], 10, 64)
+		if err != nil || t > expiry {
+			continue
+		}
+
+		err = utils.Retry(func() error {
+			return s.h.obsService.DeleteObject(p)
+		})
+		if err != nil {
+			return err
+		}
+
+		n++
+	}
+
+	if n > 0 {
+		metrics.AddFiles(domain.RepoTypeUnknown, metrics.OpDelete, n)
+
+		s.log.Infof("clean trash, %d files are deleted", n)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package app
+
+// errorRepoBusy means the repo is being synced.
+type errorRepoBusy struct {
+	error
+}
+
+func NewErrorRepoBusy(err error) errorRepoBusy {
+	return errorRepoBusy{err}
+}
+
+func IsErrorRepoBusy(err error) bool {
+	_, ok := err.(errorRepoBusy)
+
+	return ok
+}
+
+// errorRepoNotSynced means the repo has never been synced successfully.
+type errorRepoNotSynced struct {
+	error
+}
+
+func IsErrorRepoNotSynced(err error) bool {
+	_, ok := err.(errorRepoNotSynced)
+
+	return ok
+}
+
+// errorRepoTooLarge means the repo is larger than the whole workspace,
+// so it can't be synced until the workspace is expanded.
+type errorRepoTooLarge struct {
+	error
+}
+
+func NewErrorRepoTooLarge(err error) errorRepoTooLarge {
+	return errorRepoTooLarge{err}
+}
+
+func IsErrorRepoTooLarge(err error) bool {
+	_, ok := err.(errorRepoTooLarge)
+
+	return ok
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+)
+
+// lockRenewer renews the heartbeat of the running lock in the background,
+// so that the other instances will not take over it during the sync.
+type lockRenewer struct {
+	log  *logrus.Entry
+	lock synclock.RepoSyncLock
+
+	interval time.Duration
+
+	mu   sync.Mutex
+	c    domain.RepoSyncLock
+	lost bool
+
+	// ctx is canceled once the lock is lost, so that the work
+	// done under the lock can stop as soon as possible.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLockRenewer(
+	c *domain.RepoSyncLock, lock synclock.RepoSyncLock,
+	interval time.Duration, log *logrus.Entry,
+) *lockRenewer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &lockRenewer{
+		c:        *c,
+		log:      log,
+		lock:     lock,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (r *lockRenewer) start() {
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+
+			case <-t.C:
+				if !r.renew() {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// renew returns false if the lock has been taken over by others.
+func (r *lockRenewer) renew() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.c
+	c.HeartbeatAt = time.Now().Unix()
+
+	v, err := r.lock.Save(&c)
+	if err == nil {
+		r.c = v
+
+		return true
+	}
+
+	if synclock.IsErrorConcurrentUpdating(err) {
+		r.lost = true
+		r.cancel()
+
+		r.log.Errorf(
+			"the lock of repo(%s/%s) has been taken over by others",
+			c.Owner.Account(), c.RepoId,
+		)
+
+		return false
+	}
+
+	r.log.Errorf(
+		"renew the lock of repo(%s/%s) failed, err:%s",
+		c.Owner.Account(), c.RepoId, err.Error(),
+	)
+
+	return true
+}
+
+// release stops renewing and returns the latest lock.
+func (r *lockRenewer) release() (domain.RepoSyncLock, error) {
+	close(r.stop)
+	r.wg.Wait()
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lost {
+		return r.c, errors.New("the lock is lost")
+	}
+
+	return r.c, nil
+}
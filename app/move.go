@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+// MoveRepo moves the files and the lock of repo from the old owner to
+// the current one after the repo is transferred to another namespace.
+func (s *syncService) MoveRepo(info *RepoInfo, from domain.Account) error {
+	if from.Account() == info.Owner.Account() {
+		return nil
+	}
+
+	old := RepoInfo{
+		Owner:    from,
+		RepoId:   info.RepoId,
+		RepoName: info.RepoName,
+		Platform: info.Platform,
+	}
+
+	c, err := s.lock.Find(from, old.platformKey(), old.RepoId)
+	if err != nil {
+		if synclock.IsRepoSyncLockNotExist(err) {
+			// it has never been synced under the old owner.
+			return nil
+		}
+
+		return err
+	}
+
+	if c.IsDestroyed() {
+		return nil
+	}
+
+	if c.IsRunning() && !c.IsExpired(time.Now().Unix(), int64(s.cfg.LeaseTimeout)) {
+		return errorRepoBusy{errors.New("the repo is being synced")}
+	}
+
+	// target is the tombstone of repo under the new owner
+	// if the repo is transferred back.
+	var target *domain.RepoSyncLock
+
+	v, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err == nil {
+		if !v.IsDestroyed() {
+			// the repo has been synced under the new owner,
+			// so the files of old owner are stale.
+			s.log.Infof(
+				"repo(%s) has been synced, purge the old one(%s)",
+				info.repoOBSPath(), old.repoOBSPath(),
+			)
+
+			return s.DestroyRepo(&old)
+		}
+
+		target = &v
+	} else if !synclock.IsRepoSyncLockNotExist(err) {
+		return err
+	}
+
+	if c, err = s.lockRepo(&c); err != nil {
+		return err
+	}
+
+	c, ok, err := s.withLock(&c, &old, func(ctx context.Context) error {
+		n, err := s.h.movePrefix(
+			ctx,
+			s.h.getRepoObsPath(old.repoOBSPath()),
+			s.h.getRepoObsPath(info.repoOBSPath()),
+		)
+		if err == nil {
+			s.log.Infof(
+				"move repo(%s) to %s, %d files",
+				old.repoOBSPath(), info.repoOBSPath(), n,
+			)
+		}
+
+		return err
+	})
+	if !ok {
+		return err
+	}
+
+	if err != nil {
+		s.unlockRepo(&c, &old)
+
+		return err
+	}
+
+	// the repo will be synced under the new owner, which is another key.
+	s.evictMirror(&old)
+
+	if target != nil {
+		return s.reviveRepo(target, info, &c, &old)
+	}
+
+	c.Status = domain.RepoSyncStatusDone
+	c.Holder = ""
+
+	return utils.Retry(func() error {
+		_, err := s.lock.ChangeOwner(&c, info.Owner)
+		if err != nil {
+			s.log.Errorf(
+				"change owner of repo(%s) failed, err:%s",
+				old.repoOBSPath(), err.Error(),
+			)
+		}
+
+		return err
+	})
+}
+
+// reviveRepo takes over the sync state of the old lock by the tombstone,
+// and then makes the old lock a tombstone.
+func (s *syncService) reviveRepo(
+	target *domain.RepoSyncLock, info *RepoInfo,
+	c *domain.RepoSyncLock, old *RepoInfo,
+) error {
+	target.Status = domain.RepoSyncStatusDone
+	target.LastCommit = c.LastCommit
+	target.NonFastForwardAt = c.NonFastForwardAt
+
+	err := utils.Retry(func() error {
+		_, err := s.lock.Save(target)
+
+		return err
+	})
+	if err != nil {
+		s.unlockRepo(c, old)
+
+		return err
+	}
+
+	c.Status = domain.RepoSyncStatusDestroyed
+	c.LastCommit = ""
+	c.Holder = ""
+
+	s.saveLockWithRetry(c, old)
+
+	return nil
+}
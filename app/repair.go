@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+)
+
+// RepairReport is the plan of repairing if it is dry run,
+// otherwise it is what has been done.
+type RepairReport struct {
+	Owner  string `json:"owner"`
+	RepoId string `json:"repo_id"`
+	Commit string `json:"commit"`
+	DryRun bool   `json:"dry_run"`
+
+	// Uploaded are the small files which are missing or size-mismatched.
+	Uploaded      []string `json:"uploaded"`
+	UploadedBytes int64    `json:"uploaded_bytes"`
+
+	// LFSCopied are the lfs files which are copied from the lfs store again.
+	LFSCopied []string `json:"lfs_copied"`
+
+	// Deleted are the extra objects.
+	Deleted []string `json:"deleted"`
+
+	// Skipped are the lfs files which can't be repaired, because
+	// their objects are not found in the lfs store.
+	Skipped []string `json:"skipped"`
+}
+
+func (d *repoDrift) repairPlan(dryRun bool) RepairReport {
+	v := &d.report
+
+	r := RepairReport{
+		Owner:     v.Owner,
+		RepoId:    v.RepoId,
+		Commit:    v.Commit,
+		DryRun:    dryRun,
+		Uploaded:  []string{},
+		LFSCopied: []string{},
+		Deleted:   make([]string, len(v.Extra)),
+		Skipped:   v.UnresolvedLFS,
+	}
+
+	unresolved := toSet(v.UnresolvedLFS)
+
+	for _, items := range [][]FileDrift{v.Missing, v.SizeMismatched} {
+		for i := range items {
+			item := &items[i]
+
+			switch {
+			case !item.LFS:
+				r.Uploaded = append(r.Uploaded, item.Path)
+
+			case !unresolved[item.Path]:
+				r.LFSCopied = append(r.LFSCopied, item.Path)
+			}
+		}
+	}
+
+	for i := range v.Extra {
+		r.Deleted[i] = v.Extra[i].Path
+	}
+
+	return r
+}
+
+// Repair fixes the drift between obs and the tree of last synced commit
+// instead of syncing all the files again. It holds the lock of repo,
+// so that it will not race with the sync.
+func (s *syncService) Repair(info *RepoInfo, dryRun bool) (RepairReport, error) {
+	c, err := s.findSyncedLock(info)
+	if err != nil {
+		return RepairReport{}, err
+	}
+
+	ph, err := s.getPlatform(info)
+	if err != nil {
+		return RepairReport{}, err
+	}
+
+	release, err := s.reserveWorkspace(ph, info)
+	if err != nil {
+		return RepairReport{}, err
+	}
+
+	defer release()
+
+	if dryRun {
+		d, err := s.verify(info, c.LastCommit)
+
+		return d.repairPlan(true), err
+	}
+
+	if c, err = s.lockRepo(&c); err != nil {
+		return RepairReport{}, err
+	}
+
+	var r RepairReport
+
+	c, ok, err := s.withLock(&c, info, func(ctx context.Context) (err error) {
+		r, err = s.repair(ctx, info, c.LastCommit)
+
+		return
+	})
+	if ok {
+		s.unlockRepo(&c, info)
+	}
+
+	return r, err
+}
+
+func (s *syncService) repair(ctx context.Context, info *RepoInfo, commit string) (
+	r RepairReport, err error,
+) {
+	d, err := s.verify(info, commit)
+	if err != nil {
+		return
+	}
+
+	r = d.repairPlan(false)
+	obsPath := info.repoOBSPath()
+
+	if len(r.Uploaded) > 0 {
+		cloneURL, cred, err := s.remote(info)
+		if err != nil {
+			return r, err
+		}
+
+		tempDir, err := ioutil.TempDir(s.cfg.WorkDir, "repair")
+		if err != nil {
+			return r, err
+		}
+
+		defer os.RemoveAll(tempDir)
+
+		r.UploadedBytes, err = s.engine.UploadFiles(ctx, &syncengine.UploadOption{
+			WorkDir:    tempDir,
+			CloneURL:   cloneURL,
+			Credential: cred,
+			RepoKey:    obsPath,
+			Commit:     commit,
+			OBSPath:    s.h.getRepoObsPath(obsPath),
+			Files:      r.Uploaded,
+		})
+		if err != nil {
+			return r, err
+		}
+	}
+
+	if len(r.LFSCopied) > 0 {
+		files := make([]syncengine.LFSFile, len(r.LFSCopied))
+		for i, p := range r.LFSCopied {
+			files[i] = d.lfsFiles[p]
+		}
+
+		start := time.Now()
+		err = s.syncLFSFiles(ctx, files, s.h.getRepoObsPath(obsPath))
+		metrics.ObserveStage(metrics.StageLFSCopy, info.repoType(), start, err)
+		if err != nil {
+			return
+		}
+
+		metrics.AddFiles(info.repoType(), metrics.OpLFSCopy, len(files))
+	}
+
+	for _, p := range r.Deleted {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		if err = s.h.deleteObject(filepath.Join(obsPath, p)); err != nil {
+			return
+		}
+	}
+
+	metrics.AddFiles(info.repoType(), metrics.OpDelete, len(r.Deleted))
+
+	s.log.Infof(
+		"repair repo(%s), uploaded=%d, lfs copied=%d, deleted=%d, skipped=%d",
+		obsPath, len(r.Uploaded), len(r.LFSCopied), len(r.Deleted),
+		len(r.Skipped),
+	)
+
+	return
+}
+
+func toSet(v []string) map[string]bool {
+	r := make(map[string]bool, len(v))
+	for _, item := range v {
+		r[item] = true
+	}
+
+	return r
+}
@@ -1,53 +1,127 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/opensourceways/xihe-sync-repo/domain"
 	"github.com/opensourceways/xihe-sync-repo/domain/obs"
 	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
 	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
 	"github.com/opensourceways/xihe-sync-repo/utils"
 )
 
+const (
+	failureReasonLock       = "lock"
+	failureReasonLocked     = "locked"
+	failureReasonLockLost   = "lock_lost"
+	failureReasonPlatform   = "platform"
+	failureReasonSyncFile   = "sync_file"
+	failureReasonLFSCopy    = "lfs_copy"
+	failureReasonSaveCommit = "save_commit"
+	failureReasonTooLarge   = "too_large"
+)
+
 type RepoInfo struct {
 	Owner    domain.Account
 	RepoId   string
 	RepoName string
+
+	// Branch is the branch pushed. It is empty if the sync is not
+	// triggered by push, and then the tracked branch will be synced.
+	Branch string
+
+	// DefaultBranch is the default branch of repo if it is known.
+	DefaultBranch string
+
+	// Platform is the name of platform which the repo is hosted on.
+	// It is gitlab if empty.
+	Platform string
+}
+
+// platformKey returns the platform which identifies the repo together
+// with the owner and repo id. It is empty for gitlab, so that the keys
+// of repos synced before the other platforms are supported are unchanged.
+func (s *RepoInfo) platformKey() string {
+	if s.Platform == platform.Gitlab {
+		return ""
+	}
+
+	return s.Platform
+}
+
+// RepoKey identifies the repo, such as owner/repo_id for gitlab and
+// owner/github-repo_id for github. The ids of different platforms may
+// be same, but the id of gitlab is a number which has no prefix.
+func (s *RepoInfo) RepoKey() string {
+	id := s.RepoId
+	if p := s.platformKey(); p != "" {
+		id = p + "-" + id
+	}
+
+	return s.Owner.Account() + "/" + id
 }
 
 func (s *RepoInfo) repoOBSPath() string {
-	return filepath.Join(s.Owner.Account(), s.RepoId)
+	return s.RepoKey()
+}
+
+func (s *RepoInfo) repoType() string {
+	return domain.RepoTypeOfPath(s.repoOBSPath())
+}
+
+func (s *RepoInfo) platformRepo() *platform.Repo {
+	return &platform.Repo{
+		Id:    s.RepoId,
+		Owner: s.Owner.Account(),
+		Name:  s.RepoName,
+	}
 }
 
 type SyncService interface {
 	SyncRepo(*RepoInfo) error
+	IsStale(*RepoInfo) (bool, error)
+	Verify(*RepoInfo) (VerifyReport, error)
+	Repair(info *RepoInfo, dryRun bool) (RepairReport, error)
+	DestroyRepo(*RepoInfo) error
+	SnapshotTag(info *RepoInfo, tag string) error
+	DeleteTag(info *RepoInfo, tag string) error
+	MoveRepo(info *RepoInfo, from domain.Account) error
+	CleanTrash() error
+
+	GetSyncLock(*RepoInfo) (domain.RepoSyncLock, error)
+	Unlock(*RepoInfo) error
+	ResetLastCommit(*RepoInfo) error
 }
 
 func NewSyncService(
 	cfg *Config, log *logrus.Entry,
 	s obs.OBS,
-	p platform.Platform,
+	p map[string]platform.Platform,
 	l synclock.RepoSyncLock,
+	e syncengine.SyncEngine,
+	ws *Workspace,
 ) SyncService {
 	return &syncService{
 		h: &syncHelper{
 			obsService: s,
 			cfg:        cfg.HelperConfig,
 		},
-		log:       log,
-		cfg:       cfg.ServiceConfig,
-		lock:      l,
-		ph:        p,
-		obsutil:   s.OBSUtilPath(),
-		obsBucket: s.OBSBucket(),
+		log:    log,
+		cfg:    cfg.ServiceConfig,
+		lock:   l,
+		ph:     p,
+		engine: e,
+		ws:     newWorkspace(ws),
 	}
 }
 
@@ -56,15 +130,71 @@ type syncService struct {
 	log *logrus.Entry
 	cfg ServiceConfig
 
-	obsutil   string
-	obsBucket string
+	lock   synclock.RepoSyncLock
+	ph     map[string]platform.Platform
+	engine syncengine.SyncEngine
+	ws     *workspace
+}
+
+// trackedBranch returns the branch to be synced. It is empty if it
+// is the default branch and the name of default branch is unknown.
+func (s *syncService) trackedBranch(info *RepoInfo) string {
+	if v, ok := s.cfg.RepoBranches[info.repoOBSPath()]; ok {
+		return v
+	}
+
+	if s.cfg.Branch != "" {
+		return s.cfg.Branch
+	}
+
+	return info.DefaultBranch
+}
+
+// getPlatform returns the platform which the repo is hosted on.
+func (s *syncService) getPlatform(info *RepoInfo) (platform.Platform, error) {
+	name := info.Platform
+	if name == "" {
+		name = platform.Gitlab
+	}
+
+	if p, ok := s.ph[name]; ok {
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("unsupported platform:%s", name)
+}
+
+// remote returns the clone url and the credential of repo.
+func (s *syncService) remote(info *RepoInfo) (string, platform.Credential, error) {
+	p, err := s.getPlatform(info)
+	if err != nil {
+		return "", platform.Credential{}, err
+	}
 
-	lock synclock.RepoSyncLock
-	ph   platform.Platform
+	return p.GetCloneURL(info.Owner.Account(), info.RepoName), p.GetCredential(), nil
 }
 
-func (s *syncService) SyncRepo(info *RepoInfo) error {
-	c, err := s.lock.Find(info.Owner, info.RepoId)
+func (s *syncService) SyncRepo(info *RepoInfo) (err error) {
+	// the sync which fails before starting is counted too,
+	// but the one which has nothing to do is not.
+	synced := false
+	defer func() {
+		if synced || err != nil {
+			metrics.IncSync(info.repoType(), err)
+		}
+	}()
+
+	branch := s.trackedBranch(info)
+	if info.Branch != "" && branch != "" && info.Branch != branch {
+		s.log.Debugf(
+			"ignore the push to branch(%s) of repo(%s) which tracks %s",
+			info.Branch, info.repoOBSPath(), branch,
+		)
+
+		return nil
+	}
+
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
 	if err != nil {
 		if !synclock.IsRepoSyncLockNotExist(err) {
 			return err
@@ -72,47 +202,202 @@ func (s *syncService) SyncRepo(info *RepoInfo) error {
 
 		c.Owner = info.Owner
 		c.RepoId = info.RepoId
+		c.Platform = info.platformKey()
+	}
+
+	if c.IsDestroyed() {
+		s.log.Infof("repo(%s) has been destroyed, ignore it", info.repoOBSPath())
+
+		return nil
 	}
 
-	if c.Status != nil && !c.Status.IsDone() {
-		// TODO: mybe dead lock, try to unlock it and continue
+	now := time.Now().Unix()
+
+	if c.IsRunning() {
+		if !c.IsExpired(now, int64(s.cfg.LeaseTimeout)) {
+			metrics.IncSyncFailure(failureReasonLocked)
+
+			return errorRepoBusy{errors.New("can't sync, the repo is being synced")}
+		}
+
+		s.log.Warnf(
+			"the lock of repo(%s) held by %s is expired, take over it",
+			info.repoOBSPath(), c.Holder,
+		)
+	}
 
-		return errors.New("can't sync")
+	ph, err := s.getPlatform(info)
+	if err != nil {
+		return err
 	}
 
-	lastCommit, err := s.ph.GetLastCommit(info.RepoId)
+	lastCommit, err := ph.GetLastCommit(info.platformRepo(), branch)
 	if err != nil {
 		if platform.IsErrorRepoNotExists(err) {
 			return nil
 		}
 
+		metrics.IncSyncFailure(failureReasonPlatform)
+
 		return err
 	}
+
+	// the repo is empty or the branch doesn't exist, so there is nothing
+	// to clone. Keep the synced files until the branch is pushed again.
+	if lastCommit == "" {
+		if c.LastCommit != "" {
+			s.log.Infof(
+				"branch(%s) of repo(%s) has no commit, ignore it",
+				branch, info.repoOBSPath(),
+			)
+		}
+
+		return nil
+	}
+
 	if c.LastCommit == lastCommit {
 		return nil
 	}
 
-	// try lock
-	c.Status = domain.RepoSyncStatusRunning
-	c, err = s.lock.Save(&c)
+	// reserve the disk before locking, so that the lock will not
+	// expire while waiting for the other syncs.
+	release, err := s.reserveWorkspace(ph, info)
 	if err != nil {
 		return err
 	}
 
+	defer release()
+
+	// try lock
+	if c, err = s.lockRepo(&c); err != nil {
+		metrics.IncSyncFailure(failureReasonLock)
+
+		return err
+	}
+
 	// do sync
-	lastCommit, syncErr := s.doSync(c.LastCommit, info)
+	renewer := newLockRenewer(
+		&c, s.lock,
+		time.Duration(s.cfg.HeartbeatInterval)*time.Second, s.log,
+	)
+	renewer.start()
+
+	r, syncErr := s.doSync(renewer.ctx, c.LastCommit, branch, info)
+	synced = true
+
+	if c, err = renewer.release(); err != nil {
+		s.log.Errorf(
+			"sync repo(%s) is stopped, because %s, sync err:%v",
+			info.repoOBSPath(), err.Error(), syncErr,
+		)
+
+		metrics.IncSyncFailure(failureReasonLockLost)
+
+		return err
+	}
+
 	if syncErr == nil {
-		c.LastCommit = lastCommit
+		c.LastCommit = r.LastCommit
+		c.Branch = r.Branch
+
+		if r.NonFastForward {
+			c.NonFastForwardAt = time.Now().Unix()
+		}
 	}
-	c.Status = domain.RepoSyncStatusDone
 
 	// unlock
-	err = utils.Retry(func() error {
-		_, err := s.lock.Save(&c)
+	s.unlockRepo(&c, info)
+
+	return syncErr
+}
+
+// reserveWorkspace reserves the disk of workspace for the repo. It must be
+// called before any work which writes to the workspace.
+func (s *syncService) reserveWorkspace(ph platform.Platform, info *RepoInfo) (
+	func(), error,
+) {
+	release, err := s.ws.reserve(info.repoOBSPath(), s.repoSize(ph, info))
+	if err != nil && IsErrorRepoTooLarge(err) {
+		metrics.IncSyncFailure(failureReasonTooLarge)
+	}
+
+	return release, err
+}
+
+// repoSize returns the size of repo, or 0 if it is unknown.
+func (s *syncService) repoSize(ph platform.Platform, info *RepoInfo) int64 {
+	sizer, ok := ph.(platform.RepoSizer)
+	if !ok {
+		return 0
+	}
+
+	n, err := sizer.GetRepoSize(info.platformRepo())
+	if err != nil {
+		s.log.Warnf(
+			"get the size of repo(%s) failed, err:%s",
+			info.repoOBSPath(), err.Error(),
+		)
+
+		return 0
+	}
+
+	return n
+}
+
+// lockRepo marks the repo as running and holds it by this instance.
+func (s *syncService) lockRepo(c *domain.RepoSyncLock) (domain.RepoSyncLock, error) {
+	now := time.Now().Unix()
+
+	c.Status = domain.RepoSyncStatusRunning
+	c.Holder = s.cfg.Instance
+	c.StartedAt = now
+	c.HeartbeatAt = now
+
+	return s.saveLock(c)
+}
+
+// withLock runs f while renewing the lock which has been held. The ctx
+// passed to f is canceled once the lock is lost. It returns the latest
+// lock and false if the lock is lost, which means the lock must not be
+// saved any more.
+func (s *syncService) withLock(
+	c *domain.RepoSyncLock, info *RepoInfo, f func(context.Context) error,
+) (domain.RepoSyncLock, bool, error) {
+	renewer := newLockRenewer(
+		c, s.lock,
+		time.Duration(s.cfg.HeartbeatInterval)*time.Second, s.log,
+	)
+	renewer.start()
+
+	fErr := f(renewer.ctx)
+
+	v, err := renewer.release()
+	if err != nil {
+		s.log.Errorf(
+			"repo(%s) is done, but %s", info.repoOBSPath(), err.Error(),
+		)
+
+		return v, false, err
+	}
+
+	return v, true, fErr
+}
+
+func (s *syncService) unlockRepo(c *domain.RepoSyncLock, info *RepoInfo) {
+	c.Status = domain.RepoSyncStatusDone
+
+	s.saveLockWithRetry(c, info)
+}
+
+// saveLockWithRetry retries to save the lock which is held by this
+// instance, otherwise the repo can't be synced until the lease expires.
+func (s *syncService) saveLockWithRetry(c *domain.RepoSyncLock, info *RepoInfo) {
+	err := utils.Retry(func() error {
+		_, err := s.saveLock(c)
 		if err != nil {
 			s.log.Errorf(
 				"save sync repo(%s) failed, err:%s, value=%v",
-				info.repoOBSPath(), err.Error(), c,
+				info.repoOBSPath(), err.Error(), *c,
 			)
 		}
 
@@ -124,17 +409,36 @@ func (s *syncService) SyncRepo(info *RepoInfo) error {
 			info.repoOBSPath(),
 		)
 	}
+}
 
-	return syncErr
+func (s *syncService) saveLock(c *domain.RepoSyncLock) (domain.RepoSyncLock, error) {
+	info := RepoInfo{Owner: c.Owner, RepoId: c.RepoId, Platform: c.Platform}
+
+	start := time.Now()
+	v, err := s.lock.Save(c)
+	metrics.ObserveStage(metrics.StageLockSave, info.repoType(), start, err)
+
+	return v, err
 }
 
-func (s *syncService) doSync(startCommit string, info *RepoInfo) (lastCommit string, err error) {
-	if lastCommit, err = s.sync(startCommit, info); err != nil {
+func (s *syncService) doSync(
+	ctx context.Context, startCommit, branch string, info *RepoInfo,
+) (r syncengine.SyncResult, err error) {
+	if r, err = s.sync(ctx, startCommit, branch, info); err != nil {
+		return
+	}
+
+	// the new holder of lock will save it.
+	if err = ctx.Err(); err != nil {
 		return
 	}
 
-	err = s.h.saveLastCommit(info.repoOBSPath(), lastCommit)
+	start := time.Now()
+	err = s.h.saveLastCommit(s.h.getRepoObsPath(info.repoOBSPath()), r.LastCommit)
+	metrics.ObserveStage(metrics.StageSaveCommit, info.repoType(), start, err)
 	if err != nil {
+		metrics.IncSyncFailure(failureReasonSaveCommit)
+
 		s.log.Errorf(
 			"update last commit failed, err:%s",
 			err.Error(),
@@ -148,7 +452,14 @@ func (s *syncService) doSync(startCommit string, info *RepoInfo) (lastCommit str
 	return
 }
 
-func (s *syncService) sync(startCommit string, info *RepoInfo) (last string, err error) {
+func (s *syncService) sync(
+	ctx context.Context, startCommit, branch string, info *RepoInfo,
+) (r syncengine.SyncResult, err error) {
+	cloneURL, cred, err := s.remote(info)
+	if err != nil {
+		return
+	}
+
 	tempDir, err := ioutil.TempDir(s.cfg.WorkDir, "sync")
 	if err != nil {
 		return
@@ -156,62 +467,154 @@ func (s *syncService) sync(startCommit string, info *RepoInfo) (last string, err
 
 	defer os.RemoveAll(tempDir)
 
-	last, lfsFile, err := s.syncFile(tempDir, startCommit, info)
+	r, err = s.engine.Sync(ctx, &syncengine.SyncOption{
+		WorkDir:       tempDir,
+		CloneURL:      cloneURL,
+		Credential:    cred,
+		RepoKey:       info.repoOBSPath(),
+		Ref:           branch,
+		StartCommit:   startCommit,
+		OBSPath:       s.h.getRepoObsPath(info.repoOBSPath()),
+		ReservedPaths: s.h.reservedPaths(),
+	})
+	if err != nil {
+		metrics.IncSyncFailure(failureReasonSyncFile)
+
+		err = fmt.Errorf("sync file failed, err:%s", err.Error())
+
+		return
+	}
+
 	s.log.Debugf(
-		"sync file for repo:%s, last commit=%s, lfsFile=%s",
-		info.repoOBSPath(), last, lfsFile,
+		"sync file for repo:%s, branch=%s, last commit=%s, added=%d, modified=%d, "+
+			"deleted=%d, lfs=%d, uploaded bytes=%d, lfs bytes=%d, "+
+			"non-fast-forward=%t",
+		info.repoOBSPath(), r.Branch, r.LastCommit, len(r.Added), len(r.Modified),
+		len(r.Deleted), len(r.LFSFiles), r.UploadedBytes, r.LFSBytes,
+		r.NonFastForward,
 	)
-	if err != nil || lfsFile == "" {
-		return
+
+	if r.NonFastForward {
+		s.log.Warnf(
+			"non-fast-forward push of repo(%s) is detected, start commit=%s",
+			info.repoOBSPath(), startCommit,
+		)
 	}
 
-	err = s.syncLFSFiles(lfsFile, info)
+	if r.HasLFSFiles() {
+		start := time.Now()
+		err = s.syncLFSFiles(ctx, r.LFSFiles, s.h.getRepoObsPath(info.repoOBSPath()))
+		metrics.ObserveStage(metrics.StageLFSCopy, info.repoType(), start, err)
+
+		if err != nil {
+			metrics.IncSyncFailure(failureReasonLFSCopy)
+
+			return
+		}
+
+		metrics.AddFiles(info.repoType(), metrics.OpLFSCopy, len(r.LFSFiles))
+		metrics.AddBytes(info.repoType(), metrics.OpLFSCopy, r.LFSBytes)
+	}
 
 	return
 }
 
-func (s *syncService) syncLFSFiles(lfsFiles string, info *RepoInfo) error {
-	obsPath := info.repoOBSPath()
+// obsPath: the full obs path which the files are saved under
+func (s *syncService) syncLFSFiles(
+	ctx context.Context, files []syncengine.LFSFile, obsPath string,
+) error {
+	for i := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item := &files[i]
+		dst := filepath.Join(obsPath, item.Path)
 
-	return utils.ReadFileLineByLine(lfsFiles, func(line string) error {
-		v := strings.Split(line, ":oid sha256:")
-		dst := filepath.Join(obsPath, v[0])
+		s.log.Debugf("save lfs %s to %s", item.SHA, dst)
 
-		s.log.Debugf("save lfs %s to %s", v[1], dst)
+		if err := s.h.syncLFSFile(item.SHA, dst); err != nil {
+			return err
+		}
+	}
 
-		return s.h.syncLFSFile(v[1], dst)
-	})
+	return nil
 }
 
-func (s *syncService) syncFile(workDir, startCommit string, info *RepoInfo) (
-	lastCommit string, lfsFile string, err error,
-) {
-	params := []string{
-		s.cfg.SyncFileShell,
-		workDir,
-		s.ph.GetCloneURL(info.Owner.Account(), info.RepoName),
-		info.RepoName, s.obsutil, s.obsBucket,
-		s.h.getRepoObsPath(info.repoOBSPath()),
-		startCommit,
+// IsStale checks whether the synced commit falls behind the platform.
+func (s *syncService) IsStale(info *RepoInfo) (bool, error) {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil && !synclock.IsRepoSyncLockNotExist(err) {
+		return false, err
 	}
 
-	v, err, _ := utils.RunCmd(params...)
+	if c.IsRunning() || c.IsDestroyed() {
+		return false, nil
+	}
+
+	ph, err := s.getPlatform(info)
 	if err != nil {
-		params[2] = "clone_url"
-		err = fmt.Errorf(
-			"run sync shell, err=%s, params=%v,",
-			err.Error(), params,
-		)
+		return false, err
+	}
 
-		return
+	lastCommit, err := ph.GetLastCommit(info.platformRepo(), s.trackedBranch(info))
+	if err != nil {
+		if platform.IsErrorRepoNotExists(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return c.LastCommit != lastCommit, nil
+}
+
+func (s *syncService) GetSyncLock(info *RepoInfo) (domain.RepoSyncLock, error) {
+	return s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+}
+
+// Unlock releases the lock forcibly no matter who holds it.
+func (s *syncService) Unlock(info *RepoInfo) error {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil {
+		return err
+	}
+
+	if !c.IsRunning() {
+		return nil
+	}
+
+	s.log.Warnf(
+		"unlock repo(%s) held by %s forcibly", info.repoOBSPath(), c.Holder,
+	)
+
+	c.Status = domain.RepoSyncStatusDone
+	c.Holder = ""
+
+	_, err = s.lock.Save(&c)
+
+	return err
+}
+
+// ResetLastCommit clears the last commit, so that the next sync will
+// sync all the files of repo.
+func (s *syncService) ResetLastCommit(info *RepoInfo) error {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil {
+		return err
 	}
 
-	r := strings.Split(string(v), ", ")
-	lastCommit = r[0]
+	if c.IsRunning() {
+		return errorRepoBusy{errors.New("the repo is being synced")}
+	}
 
-	if strings.HasPrefix(r[2], "yes") {
-		lfsFile = r[1]
+	if c.LastCommit == "" {
+		return nil
 	}
 
-	return
+	c.LastCommit = ""
+
+	_, err = s.lock.Save(&c)
+
+	return err
 }
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"path/filepath"
+	"strings"
 
 	"github.com/opensourceways/xihe-sync-repo/domain/obs"
 	"github.com/opensourceways/xihe-sync-repo/utils"
@@ -13,26 +15,152 @@ type syncHelper struct {
 }
 
 // sha: sha
-// dst: user/[project,model,dataset]/repo_id/xxx
+// dst: the full obs path of file
 func (s *syncHelper) syncLFSFile(sha, dst string) error {
 	return utils.Retry(func() error {
-		return s.obsService.CopyObject(
-			filepath.Join(s.cfg.RepoPath, dst),
-			filepath.Join(s.cfg.LFSPath, sha[:2], sha[2:4], sha[4:]),
-		)
+		return s.obsService.CopyObject(dst, s.lfsObjectPath(sha))
+	})
+}
+
+// p: user/[project,model,dataset]/repo_id/xxx
+func (s *syncHelper) deleteObject(p string) error {
+	return utils.Retry(func() error {
+		return s.obsService.DeleteObject(filepath.Join(s.cfg.RepoPath, p))
 	})
 }
 
+// deletePrefix deletes all the objects under the directory p
+// and returns the number of them.
+func (s *syncHelper) deletePrefix(ctx context.Context, p string) (int, error) {
+	objs, err := s.obsService.ListObjects(p + "/")
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range objs {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		err := utils.Retry(func() error {
+			return s.obsService.DeleteObject(objs[i].Path)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(objs), nil
+}
+
+// movePrefix moves all the objects under the directory src to dst
+// and returns the number of them.
+func (s *syncHelper) movePrefix(ctx context.Context, src, dst string) (int, error) {
+	objs, err := s.obsService.ListObjects(src + "/")
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range objs {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		p := objs[i].Path
+
+		err := utils.Retry(func() error {
+			return s.obsService.CopyObject(
+				filepath.Join(dst, strings.TrimPrefix(p, src+"/")), p,
+			)
+		})
+		if err != nil {
+			return i, err
+		}
+
+		err = utils.Retry(func() error {
+			return s.obsService.DeleteObject(p)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(objs), nil
+}
+
+// lfsObjectPath returns the path of lfs object in the lfs store.
+func (s *syncHelper) lfsObjectPath(sha string) string {
+	return filepath.Join(s.cfg.LFSPath, sha[:2], sha[2:4], sha[4:])
+}
+
+// statObject returns the info of object at p and whether it exists.
+func (s *syncHelper) statObject(p string) (obs.ObjectInfo, bool, error) {
+	objs, err := s.obsService.ListObjects(p)
+	if err != nil {
+		return obs.ObjectInfo{}, false, err
+	}
+
+	for i := range objs {
+		if objs[i].Path == p {
+			return objs[i], true, nil
+		}
+	}
+
+	return obs.ObjectInfo{}, false, nil
+}
+
+// listRepoObjects lists the objects of repo except the reserved paths.
+// The keys of result are the paths relative to the obs path of repo.
 // p: user/[project,model,dataset]/repo_id
-func (s *syncHelper) saveLastCommit(p, commit string) error {
+func (s *syncHelper) listRepoObjects(p string) (map[string]int64, error) {
+	prefix := s.getRepoObsPath(p) + "/"
+
+	objs, err := s.obsService.ListObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string]int64, len(objs))
+	for i := range objs {
+		k := strings.TrimPrefix(objs[i].Path, prefix)
+		if !s.isReserved(k) {
+			r[k] = objs[i].Size
+		}
+	}
+
+	return r, nil
+}
+
+// dir: the full obs path of repo or snapshot of tag
+func (s *syncHelper) saveLastCommit(dir, commit string) error {
 	return utils.Retry(func() error {
 		return s.obsService.SaveObject(
-			filepath.Join(s.cfg.RepoPath, p, s.cfg.CommitFile),
-			commit,
+			filepath.Join(dir, s.cfg.CommitFile), commit,
 		)
 	})
 }
 
+// reservedPaths returns the paths under the obs path of repo which
+// are not the files of repo.
+func (s *syncHelper) reservedPaths() []string {
+	return []string{s.cfg.CommitFile, s.cfg.TagPath + "/"}
+}
+
+// p: user/[project,model,dataset]/repo_id
+func (s *syncHelper) tagOBSPath(p, tag string) string {
+	return filepath.Join(s.getRepoObsPath(p), s.cfg.TagPath, tag)
+}
+
+func (s *syncHelper) isReserved(p string) bool {
+	for _, v := range s.reservedPaths() {
+		if p == v || strings.HasPrefix(p, strings.TrimSuffix(v, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // p: user/[project,model,dataset]/repo_id
 func (s *syncHelper) getRepoObsPath(p string) string {
 	return filepath.Join(s.cfg.RepoPath, p)
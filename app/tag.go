@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+)
+
+// SnapshotTag saves the tree of tag to the tag path of repo. The snapshot
+// is immutable, so it will not be changed even if the tag is moved.
+func (s *syncService) SnapshotTag(info *RepoInfo, tag string) error {
+	p := s.h.tagOBSPath(info.repoOBSPath(), tag)
+
+	_, exists, err := s.h.statObject(filepath.Join(p, s.h.cfg.CommitFile))
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		s.log.Infof("the snapshot of tag(%s) exists, ignore it", p)
+
+		return nil
+	}
+
+	ph, err := s.getPlatform(info)
+	if err != nil {
+		return err
+	}
+
+	release, err := s.reserveWorkspace(ph, info)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	return s.runLocked(info, func(ctx context.Context) error {
+		return s.snapshot(ctx, info, tag, p)
+	})
+}
+
+// DeleteTag deletes the snapshot of tag according to the policy.
+func (s *syncService) DeleteTag(info *RepoInfo, tag string) error {
+	if s.cfg.TagDeletePolicy != TagDeletePolicyDelete {
+		return nil
+	}
+
+	return s.runLocked(info, func(ctx context.Context) error {
+		return s.purge(ctx, info, s.h.tagOBSPath(info.repoOBSPath(), tag))
+	})
+}
+
+// runLocked runs f while holding the lock of repo, so that it will not
+// race with the sync. It does nothing if the repo has been destroyed.
+func (s *syncService) runLocked(info *RepoInfo, f func(context.Context) error) error {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil {
+		if !synclock.IsRepoSyncLockNotExist(err) {
+			return err
+		}
+
+		c.Owner = info.Owner
+		c.RepoId = info.RepoId
+		c.Platform = info.platformKey()
+	}
+
+	if c.IsDestroyed() {
+		return nil
+	}
+
+	if c.IsRunning() && !c.IsExpired(time.Now().Unix(), int64(s.cfg.LeaseTimeout)) {
+		return errorRepoBusy{errors.New("the repo is being synced")}
+	}
+
+	if c, err = s.lockRepo(&c); err != nil {
+		return err
+	}
+
+	c, ok, err := s.withLock(&c, info, f)
+	if ok {
+		s.unlockRepo(&c, info)
+	}
+
+	return err
+}
+
+// p: repo_path/user/[project,model,dataset]/repo_id/tag_path/tag
+func (s *syncService) snapshot(ctx context.Context, info *RepoInfo, tag, p string) error {
+	cloneURL, cred, err := s.remote(info)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := ioutil.TempDir(s.cfg.WorkDir, "tag")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tempDir)
+
+	r, err := s.engine.Sync(ctx, &syncengine.SyncOption{
+		WorkDir:    tempDir,
+		CloneURL:   cloneURL,
+		Credential: cred,
+		RepoKey:    info.repoOBSPath(),
+		Ref:        tag,
+		OBSPath:    p,
+	})
+	if err != nil {
+		metrics.IncSyncFailure(failureReasonSyncFile)
+
+		return fmt.Errorf("snapshot tag failed, err:%s", err.Error())
+	}
+
+	if r.HasLFSFiles() {
+		start := time.Now()
+		err = s.syncLFSFiles(ctx, r.LFSFiles, p)
+		metrics.ObserveStage(metrics.StageLFSCopy, info.repoType(), start, err)
+
+		if err != nil {
+			metrics.IncSyncFailure(failureReasonLFSCopy)
+
+			return err
+		}
+
+		metrics.AddFiles(info.repoType(), metrics.OpLFSCopy, len(r.LFSFiles))
+		metrics.AddBytes(info.repoType(), metrics.OpLFSCopy, r.LFSBytes)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// the commit file marks the snapshot is complete.
+	if err = s.h.saveLastCommit(p, r.LastCommit); err != nil {
+		metrics.IncSyncFailure(failureReasonSaveCommit)
+
+		return err
+	}
+
+	s.log.Infof(
+		"snapshot tag(%s) at commit %s, files=%d, lfs=%d",
+		p, r.LastCommit, len(r.Added), len(r.LFSFiles),
+	)
+
+	return nil
+}
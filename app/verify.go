@@ -0,0 +1,201 @@
+package app
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+)
+
+type FileDrift struct {
+	Path string `json:"path"`
+	LFS  bool   `json:"lfs,omitempty"`
+
+	// ExpectedSize is the size of file in the tree, or the size of
+	// lfs object if it is a lfs file. It is 0 for the extra file.
+	ExpectedSize int64 `json:"expected_size"`
+
+	// ActualSize is the size of object in obs. It is 0 for the missing file.
+	ActualSize int64 `json:"actual_size"`
+}
+
+type VerifyReport struct {
+	Owner  string `json:"owner"`
+	RepoId string `json:"repo_id"`
+	Commit string `json:"commit"`
+
+	// Total is the number of files in the tree.
+	Total int `json:"total"`
+
+	Missing        []FileDrift `json:"missing"`
+	Extra          []FileDrift `json:"extra"`
+	SizeMismatched []FileDrift `json:"size_mismatched"`
+
+	// UnresolvedLFS are the lfs files whose objects can't be found
+	// in the lfs store.
+	UnresolvedLFS []string `json:"unresolved_lfs"`
+}
+
+func (r *VerifyReport) IsConsistent() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.SizeMismatched) == 0
+}
+
+// repoDrift is the difference between obs and the tree of commit.
+type repoDrift struct {
+	report VerifyReport
+
+	// lfsFiles are the lfs files which are missing or size-mismatched.
+	lfsFiles map[string]syncengine.LFSFile
+}
+
+// Verify compares the objects in obs with the tree of last synced commit.
+func (s *syncService) Verify(info *RepoInfo) (VerifyReport, error) {
+	c, err := s.findSyncedLock(info)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	ph, err := s.getPlatform(info)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	// the mirror of repo may be fetched to the workspace.
+	release, err := s.reserveWorkspace(ph, info)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	defer release()
+
+	d, err := s.verify(info, c.LastCommit)
+
+	return d.report, err
+}
+
+// findSyncedLock returns the lock of repo which has been synced and
+// is not being synced.
+func (s *syncService) findSyncedLock(info *RepoInfo) (domain.RepoSyncLock, error) {
+	c, err := s.lock.Find(info.Owner, info.platformKey(), info.RepoId)
+	if err != nil {
+		if synclock.IsRepoSyncLockNotExist(err) {
+			err = errorRepoNotSynced{errors.New("the repo has not been synced")}
+		}
+
+		return c, err
+	}
+
+	if c.IsRunning() {
+		return c, errorRepoBusy{errors.New("the repo is being synced")}
+	}
+
+	if c.IsDestroyed() {
+		return c, errorRepoNotSynced{errors.New("the repo has been destroyed")}
+	}
+
+	if c.LastCommit == "" {
+		return c, errorRepoNotSynced{errors.New("the repo has not been synced")}
+	}
+
+	return c, nil
+}
+
+func (s *syncService) verify(info *RepoInfo, commit string) (d repoDrift, err error) {
+	cloneURL, cred, err := s.remote(info)
+	if err != nil {
+		return
+	}
+
+	files, err := s.engine.ListTree(&syncengine.TreeOption{
+		CloneURL:   cloneURL,
+		Credential: cred,
+		RepoKey:    info.repoOBSPath(),
+		Commit:     commit,
+	})
+	if err != nil {
+		return
+	}
+
+	objs, err := s.h.listRepoObjects(info.repoOBSPath())
+	if err != nil {
+		return
+	}
+
+	r := &d.report
+	r.Owner = info.Owner.Account()
+	r.RepoId = info.RepoId
+	r.Commit = commit
+	r.Missing = []FileDrift{}
+	r.Extra = []FileDrift{}
+	r.SizeMismatched = []FileDrift{}
+	r.UnresolvedLFS = []string{}
+	d.lfsFiles = map[string]syncengine.LFSFile{}
+
+	for i := range files {
+		f := &files[i]
+		if s.h.isReserved(f.Path) {
+			continue
+		}
+
+		r.Total++
+
+		actual, ok := objs[f.Path]
+		delete(objs, f.Path)
+
+		item := FileDrift{
+			Path:         f.Path,
+			LFS:          f.LFS != nil,
+			ExpectedSize: f.Size,
+			ActualSize:   actual,
+		}
+
+		if f.LFS != nil {
+			item.ExpectedSize = f.LFS.Size
+		}
+
+		if ok && item.ActualSize == item.ExpectedSize {
+			continue
+		}
+
+		if f.LFS != nil {
+			// the size in pointer may be inaccurate, check the lfs store.
+			obj, found, err := s.h.statObject(s.h.lfsObjectPath(f.LFS.SHA))
+			if err != nil {
+				return d, err
+			}
+
+			if !found {
+				r.UnresolvedLFS = append(r.UnresolvedLFS, f.Path)
+			} else {
+				item.ExpectedSize = obj.Size
+
+				if ok && item.ActualSize == item.ExpectedSize {
+					continue
+				}
+			}
+
+			d.lfsFiles[f.Path] = *f.LFS
+		}
+
+		if ok {
+			r.SizeMismatched = append(r.SizeMismatched, item)
+		} else {
+			r.Missing = append(r.Missing, item)
+		}
+	}
+
+	for p, size := range objs {
+		r.Extra = append(r.Extra, FileDrift{
+			Path:       p,
+			ActualSize: size,
+		})
+	}
+
+	sort.Slice(r.Extra, func(i, j int) bool {
+		return r.Extra[i].Path < r.Extra[j].Path
+	})
+
+	return
+}
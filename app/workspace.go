@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+)
+
+// Workspace is the disk space of work dir shared by the syncs.
+type Workspace struct {
+	// Capacity is the bytes which the syncs can take in total.
+	Capacity int64
+
+	// DefaultRepoSize is the estimated bytes of repo whose size is unknown.
+	DefaultRepoSize int64
+
+	// SizeFactor is the ratio of the disk taken by a repo to the size of
+	// its git objects, because the files checked out are uncompressed.
+	SizeFactor int64
+}
+
+// workspace reserves the disk space for each sync by the size of repo,
+// so that the syncs running at the same time will not exhaust the disk.
+type workspace struct {
+	Workspace
+
+	sem *semaphore.Weighted
+}
+
+func newWorkspace(cfg *Workspace) *workspace {
+	return &workspace{
+		Workspace: *cfg,
+		sem:       semaphore.NewWeighted(cfg.Capacity),
+	}
+}
+
+// reserve waits until there is enough space for the repo whose git
+// objects are size bytes, and returns the function to release it.
+// The default size is reserved if size is unknown.
+func (w *workspace) reserve(repo string, size int64) (func(), error) {
+	if size > 0 {
+		size *= w.SizeFactor
+	} else {
+		size = w.DefaultRepoSize
+	}
+
+	if size > w.Capacity {
+		return nil, errorRepoTooLarge{
+			fmt.Errorf(
+				"repo(%s) of %d bytes is larger than the workspace of %d bytes",
+				repo, size, w.Capacity,
+			),
+		}
+	}
+
+	// it never fails, because the context is never done.
+	if err := w.sem.Acquire(context.Background(), size); err != nil {
+		return nil, err
+	}
+
+	metrics.ReserveWorkspace(size)
+
+	return func() {
+		w.sem.Release(size)
+
+		metrics.ReleaseWorkspace(size)
+	}, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+)
+
+// The exit codes of subcommands.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitDrift = 2
+)
+
+// subcommand runs the operation once and returns the exit code.
+type subcommand func(log *logrus.Entry, args []string) int
+
+var subcommands = map[string]subcommand{
+	"verify": runVerify,
+	"repair": runRepair,
+}
+
+type repoOptions struct {
+	configFile string
+	owner      string
+	repoId     string
+	repoName   string
+	platform   string
+}
+
+func (o *repoOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configFile, "config-file", "", "Path to config file.")
+	fs.StringVar(&o.owner, "owner", "", "Owner of the repo.")
+	fs.StringVar(&o.repoId, "repo-id", "", "Id of the repo.")
+	fs.StringVar(&o.repoName, "repo-name", "", "Name of the repo.")
+	fs.StringVar(&o.platform, "platform", "", "Platform of the repo, gitlab by default.")
+}
+
+func (o *repoOptions) repoInfo() (app.RepoInfo, error) {
+	if o.configFile == "" {
+		return app.RepoInfo{}, errors.New("missing config-file")
+	}
+
+	if o.repoId == "" || o.repoName == "" {
+		return app.RepoInfo{}, errors.New("missing repo-id or repo-name")
+	}
+
+	owner, err := domain.NewAccount(o.owner)
+	if err != nil {
+		return app.RepoInfo{}, err
+	}
+
+	return app.RepoInfo{
+		Owner:    owner,
+		RepoId:   o.repoId,
+		RepoName: o.repoName,
+		Platform: o.platform,
+	}, nil
+}
+
+// runRepoCommand parses the options and runs cmd with the sync service.
+func runRepoCommand(
+	name string, log *logrus.Entry, args []string,
+	addFlags func(*flag.FlagSet),
+	cmd func(app.SyncService, *app.RepoInfo) int,
+) int {
+	var o repoOptions
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	o.addFlags(fs)
+
+	if addFlags != nil {
+		addFlags(fs)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		log.Errorf("parse options failed, err:%s", err.Error())
+
+		return exitError
+	}
+
+	info, err := o.repoInfo()
+	if err != nil {
+		log.Errorf("invalid options, err:%s", err.Error())
+
+		return exitError
+	}
+
+	cfg, err := loadConfig(o.configFile)
+	if err != nil {
+		log.Errorf("Error loading config, err:%v", err)
+
+		return exitError
+	}
+
+	// the mirrors of daemon can't be shared, use a temporary one.
+	mirrorDir, err := ioutil.TempDir(cfg.App.WorkDir, "mirrors")
+	if err != nil {
+		log.Errorf("create mirror dir failed, err:%s", err.Error())
+
+		return exitError
+	}
+
+	defer os.RemoveAll(mirrorDir)
+
+	s, err := initServices(&cfg, log, mirrorDir)
+	if err != nil {
+		log.Errorf("init services failed, err:%s", err.Error())
+
+		return exitError
+	}
+
+	return cmd(s.service, &info)
+}
+
+// runVerify prints the report of verifying the repo as json. It exits
+// with exitDrift if the obs does not match the git tree.
+func runVerify(log *logrus.Entry, args []string) int {
+	return runRepoCommand(
+		"verify", log, args, nil,
+		func(s app.SyncService, info *app.RepoInfo) int {
+			report, err := s.Verify(info)
+			if err != nil {
+				log.Errorf("verify repo failed, err:%s", err.Error())
+
+				return exitError
+			}
+
+			if err := printJSON(report); err != nil {
+				log.Errorf("print report failed, err:%s", err.Error())
+
+				return exitError
+			}
+
+			if !report.IsConsistent() {
+				return exitDrift
+			}
+
+			return exitOK
+		},
+	)
+}
+
+// runRepair prints the plan of repairing the repo as json if it is
+// dry run, otherwise it repairs the repo and prints what has been done.
+func runRepair(log *logrus.Entry, args []string) int {
+	dryRun := false
+
+	return runRepoCommand(
+		"repair", log, args,
+		func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "Print the plan only.")
+		},
+		func(s app.SyncService, info *app.RepoInfo) int {
+			report, err := s.Repair(info, dryRun)
+
+			if err1 := printJSON(report); err1 != nil {
+				log.Errorf("print report failed, err:%s", err1.Error())
+			}
+
+			if err != nil {
+				log.Errorf("repair repo failed, err:%s", err.Error())
+
+				return exitError
+			}
+
+			return exitOK
+		},
+	)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
-	"github.com/opensourceways/community-robot-lib/utils"
+	"errors"
+	"fmt"
+
+	libutils "github.com/opensourceways/community-robot-lib/utils"
 
 	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/localobsimpl"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/mysql"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/obsimpl"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/platformimpl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/s3impl"
+	"github.com/opensourceways/xihe-sync-repo/server"
 	"github.com/opensourceways/xihe-sync-repo/syncrepo"
+	"github.com/opensourceways/xihe-sync-repo/utils"
 )
 
 type configValidate interface {
@@ -18,29 +26,148 @@ type configSetDefault interface {
 	SetDefault()
 }
 
+const (
+	storageOBS   = "obs"
+	storageLocal = "local"
+	storageS3    = "s3"
+)
+
 type configuration struct {
-	App      app.Config          `json:"app"       required:"true"`
-	OBS      obsimpl.Config      `json:"obs"       required:"true"`
-	Mysql    mysql.Config        `json:"mysql"     required:"true"`
-	Gitlab   platformimpl.Config `json:"gitlab"    required:"true"`
-	SyncRepo syncrepo.Config     `json:"syncrepo"  required:"true"`
+	App      app.Config      `json:"app"       required:"true"`
+	Mysql    mysql.Config    `json:"mysql"     required:"true"`
+	SyncRepo syncrepo.Config `json:"syncrepo"  required:"true"`
+	Server   server.Config   `json:"server"    required:"true"`
+
+	// The platforms which the repos are hosted on.
+	// The platform which each topic comes from must be set.
+	Gitlab *platformimpl.Config       `json:"gitlab"`
+	Github *platformimpl.GithubConfig `json:"github"`
+	Gitea  *platformimpl.GiteaConfig  `json:"gitea"`
+	Gitee  *platformimpl.GiteeConfig  `json:"gitee"`
+	Git    *platformimpl.GitConfig    `json:"git"`
+
+	// Storage is the backend which the repo files are synced to.
+	// It can be obs, local or s3 and is obs by default.
+	Storage  string               `json:"storage"`
+	OBS      *obsimpl.Config      `json:"obs"`
+	LocalOBS *localobsimpl.Config `json:"local_obs"`
+	S3       *s3impl.Config       `json:"s3"`
 }
 
 func (cfg *configuration) configItems() []interface{} {
-	return []interface{}{
+	items := []interface{}{
 		&cfg.App,
-		&cfg.OBS,
-		&cfg.Gitlab,
 		&cfg.Mysql,
 		&cfg.SyncRepo,
+		&cfg.Server,
+	}
+
+	if cfg.Gitlab != nil {
+		items = append(items, cfg.Gitlab)
+	}
+
+	if cfg.Github != nil {
+		items = append(items, cfg.Github)
+	}
+
+	if cfg.Gitea != nil {
+		items = append(items, cfg.Gitea)
+	}
+
+	if cfg.Gitee != nil {
+		items = append(items, cfg.Gitee)
+	}
+
+	if cfg.Git != nil {
+		items = append(items, cfg.Git)
+	}
+
+	if cfg.OBS != nil {
+		items = append(items, cfg.OBS)
+	}
+
+	if cfg.LocalOBS != nil {
+		items = append(items, cfg.LocalOBS)
+	}
+
+	if cfg.S3 != nil {
+		items = append(items, cfg.S3)
 	}
+
+	return items
+}
+
+func (cfg *configuration) validateStorage() error {
+	switch cfg.Storage {
+	case storageOBS:
+		if cfg.OBS == nil {
+			return errors.New("missing obs")
+		}
+
+	case storageLocal:
+		if cfg.LocalOBS == nil {
+			return errors.New("missing local_obs")
+		}
+
+	case storageS3:
+		if cfg.S3 == nil {
+			return errors.New("missing s3")
+		}
+
+	default:
+		return errors.New("unknown storage")
+	}
+
+	return nil
+}
+
+// platforms returns the names of platforms set.
+func (cfg *configuration) platforms() map[string]bool {
+	return map[string]bool{
+		platform.Gitlab: cfg.Gitlab != nil,
+		platform.Github: cfg.Github != nil,
+		platform.Gitea:  cfg.Gitea != nil,
+		platform.Gitee:  cfg.Gitee != nil,
+		platform.Git:    cfg.Git != nil,
+	}
+}
+
+func (cfg *configuration) validatePlatforms() error {
+	platforms := cfg.platforms()
+
+	for _, item := range cfg.SyncRepo.AllTopics() {
+		if !platforms[item.Platform] {
+			return fmt.Errorf(
+				"missing %s which topic:%s needs", item.Platform, item.Topic,
+			)
+		}
+	}
+
+	if cfg.SyncRepo.Reconcile.Interval > 0 && cfg.Gitlab == nil {
+		return errors.New("missing gitlab which the reconciliation needs")
+	}
+
+	return nil
 }
 
 func (cfg *configuration) validate() error {
-	if _, err := utils.BuildRequestBody(cfg, ""); err != nil {
+	if _, err := libutils.BuildRequestBody(cfg, ""); err != nil {
+		return err
+	}
+
+	if err := cfg.validateStorage(); err != nil {
 		return err
 	}
 
+	if err := cfg.validatePlatforms(); err != nil {
+		return err
+	}
+
+	if cfg.SyncRepo.Dedup.Store == syncrepo.DedupStoreMysql &&
+		cfg.Mysql.DedupTableName == "" {
+		return errors.New("missing dedup_table_name of mysql")
+	}
+
 	items := cfg.configItems()
 
 	for _, i := range items {
@@ -55,6 +182,10 @@ func (cfg *configuration) validate() error {
 }
 
 func (cfg *configuration) setDefault() {
+	if cfg.Storage == "" {
+		cfg.Storage = storageOBS
+	}
+
 	items := cfg.configItems()
 
 	for _, i := range items {
@@ -64,14 +195,53 @@ func (cfg *configuration) setDefault() {
 	}
 }
 
+// secrets returns the secrets which should not be logged.
+func (cfg *configuration) secrets() []string {
+	v := []string{cfg.Server.Token, cfg.Mysql.Password()}
+
+	for _, item := range cfg.SyncRepo.AllTopics() {
+		v = append(v, item.Verify.Secrets...)
+	}
+
+	if cfg.Gitlab != nil {
+		v = append(v, cfg.Gitlab.Token)
+	}
+
+	if cfg.Github != nil {
+		v = append(v, cfg.Github.Token)
+	}
+
+	if cfg.Gitea != nil {
+		v = append(v, cfg.Gitea.Token)
+	}
+
+	if cfg.Gitee != nil {
+		v = append(v, cfg.Gitee.Token)
+	}
+
+	if cfg.OBS != nil {
+		v = append(v, cfg.OBS.AccessKey, cfg.OBS.SecretKey)
+	}
+
+	if cfg.S3 != nil {
+		v = append(v, cfg.S3.AccessKey, cfg.S3.SecretKey)
+	}
+
+	return v
+}
+
 func loadConfig(file string) (cfg configuration, err error) {
-	if err = utils.LoadFromYaml(file, &cfg); err != nil {
+	if err = libutils.LoadFromYaml(file, &cfg); err != nil {
 		return
 	}
 
 	cfg.setDefault()
 
-	err = cfg.validate()
+	if err = cfg.validate(); err != nil {
+		return
+	}
+
+	utils.AddSecrets(cfg.secrets()...)
 
 	return
 }
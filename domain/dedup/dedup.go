@@ -0,0 +1,14 @@
+package dedup
+
+// EventDedup records the events which have been handled,
+// so that the duplicate ones delivered again can be dropped.
+type EventDedup interface {
+	// IsHandled returns true if the event has been recorded
+	// and the record has not expired.
+	IsHandled(eventId string) (bool, error)
+
+	// MarkHandled records the event. It should be called only after
+	// the event is handled successfully or put to the retry queue,
+	// otherwise the event will be lost if the instance exits before that.
+	MarkHandled(eventId string) error
+}
@@ -10,12 +10,31 @@ const (
 	resourceProject = "project"
 	resourceDataset = "dataset"
 	resourceModel   = "model"
+
+	// RepoTypeUnknown is the type of repo whose obs path has no type.
+	RepoTypeUnknown = "unknown"
 )
 
 var (
 	reName = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
 )
 
+// RepoTypeOfPath returns the resource type of repo by its obs path,
+// such as model for user/model/repo_id.
+func RepoTypeOfPath(p string) string {
+	v := strings.Split(p, "/")
+	if len(v) < 3 {
+		return RepoTypeUnknown
+	}
+
+	switch v[1] {
+	case resourceProject, resourceDataset, resourceModel:
+		return v[1]
+	}
+
+	return RepoTypeUnknown
+}
+
 // Account
 type Account interface {
 	Account() string
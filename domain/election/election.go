@@ -0,0 +1,9 @@
+package election
+
+// Elector elects the leader among the instances.
+type Elector interface {
+	// TryAcquire makes holder the leader of name for lease seconds if
+	// there is no leader or the lease of leader has expired. It also
+	// renews the lease if holder is the leader already.
+	TryAcquire(name, holder string, lease int64) (bool, error)
+}
@@ -1,9 +1,15 @@
 package obs
 
+type ObjectInfo struct {
+	Path string
+	Size int64
+}
+
 type OBS interface {
 	SaveObject(path, content string) error
+	SaveFile(path, localFile string) error
 	GetObject(path string) ([]byte, error)
 	CopyObject(dst, src string) error
-	OBSUtilPath() string
-	OBSBucket() string
+	DeleteObject(path string) error
+	ListObjects(prefix string) ([]ObjectInfo, error)
 }
@@ -0,0 +1,162 @@
+// Package obstest checks the implementations of obs.OBS
+// behave the same, so that they can replace each other.
+package obstest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/obs"
+)
+
+// TestOBS saves, copies, lists and deletes the objects in s, which
+// must be empty at the beginning.
+func TestOBS(t *testing.T, s obs.OBS) {
+	t.Run("save and get object", func(t *testing.T) {
+		p := "repos/owner/1/.commit"
+
+		mustSaveObject(t, s, p, "v1")
+		checkObject(t, s, p, "v1")
+
+		// overwrite
+		mustSaveObject(t, s, p, "v2")
+		checkObject(t, s, p, "v2")
+	})
+
+	t.Run("get missing object", func(t *testing.T) {
+		v, err := s.GetObject("repos/owner/1/missing")
+		if err != nil || v != nil {
+			t.Errorf("got (%q, %v), want (nil, nil)", v, err)
+		}
+	})
+
+	t.Run("save file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, []byte("hello world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		p := "repos/owner/2/dir/a b/文件.txt"
+		if err := s.SaveFile(p, file); err != nil {
+			t.Fatal(err)
+		}
+
+		checkObject(t, s, p, "hello world")
+	})
+
+	t.Run("copy object", func(t *testing.T) {
+		src, dst := "lfs/ab/cd/ef", "repos/owner/2/model.bin"
+
+		mustSaveObject(t, s, src, "lfs content")
+
+		if err := s.CopyObject(dst, src); err != nil {
+			t.Fatal(err)
+		}
+
+		checkObject(t, s, dst, "lfs content")
+		checkObject(t, s, src, "lfs content")
+	})
+
+	t.Run("list objects", func(t *testing.T) {
+		mustSaveObject(t, s, "repos/owner/20/file", "x")
+
+		cases := []struct {
+			prefix string
+			want   map[string]int64
+		}{
+			{
+				prefix: "repos/owner/2/",
+				want: map[string]int64{
+					"repos/owner/2/dir/a b/文件.txt": 11,
+					"repos/owner/2/model.bin":      11,
+				},
+			},
+			{
+				// the prefix may be a part of name
+				prefix: "repos/owner/2",
+				want: map[string]int64{
+					"repos/owner/2/dir/a b/文件.txt": 11,
+					"repos/owner/2/model.bin":      11,
+					"repos/owner/20/file":          1,
+				},
+			},
+			{
+				// the prefix is the object itself
+				prefix: "repos/owner/1/.commit",
+				want: map[string]int64{
+					"repos/owner/1/.commit": 2,
+				},
+			},
+			{
+				prefix: "repos/nobody/",
+				want:   map[string]int64{},
+			},
+		}
+
+		for _, c := range cases {
+			if v := listObjects(t, s, c.prefix); !reflect.DeepEqual(v, c.want) {
+				t.Errorf("list %s: got %v, want %v", c.prefix, v, c.want)
+			}
+		}
+	})
+
+	t.Run("delete object", func(t *testing.T) {
+		p := "repos/owner/2/model.bin"
+
+		if err := s.DeleteObject(p); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := s.GetObject(p); err != nil || v != nil {
+			t.Errorf("got (%q, %v) after deleting, want (nil, nil)", v, err)
+		}
+
+		if v := listObjects(t, s, p); len(v) != 0 {
+			t.Errorf("got %v after deleting, want empty", v)
+		}
+
+		// deleting the missing object is not an error.
+		if err := s.DeleteObject(p); err != nil {
+			t.Errorf("delete the missing object: %v", err)
+		}
+	})
+}
+
+func mustSaveObject(t *testing.T, s obs.OBS, p, content string) {
+	t.Helper()
+
+	if err := s.SaveObject(p, content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkObject(t *testing.T, s obs.OBS, p, want string) {
+	t.Helper()
+
+	v, err := s.GetObject(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(v) != want {
+		t.Errorf("get %s: got %q, want %q", p, v, want)
+	}
+}
+
+func listObjects(t *testing.T, s obs.OBS, prefix string) map[string]int64 {
+	t.Helper()
+
+	objs, err := s.ListObjects(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := make(map[string]int64, len(objs))
+	for _, item := range objs {
+		r[item.Path] = item.Size
+	}
+
+	return r
+}
@@ -1,6 +1,56 @@
 package platform
 
+// The names of platforms which the repos are hosted on.
+const (
+	Gitlab = "gitlab"
+	Github = "github"
+	Gitea  = "gitea"
+	Gitee  = "gitee"
+
+	// Git is the git server which has no api.
+	Git = "git"
+)
+
 type Platform interface {
-	GetLastCommit(pid string) (string, error)
+	// GetLastCommit returns the last commit of branch.
+	// It is the default branch if branch is empty.
+	GetLastCommit(repo *Repo, branch string) (string, error)
+
+	// GetCloneURL returns the url without credential.
 	GetCloneURL(owner, repo string) string
+
+	// GetCredential returns the credential to clone the repos.
+	GetCredential() Credential
+}
+
+// Credential is used to clone the repo by http(s). It is passed to git
+// separately instead of being embedded in the clone url, so that it will
+// not be exposed by the command line and the output of git.
+type Credential struct {
+	Username string
+	Password string
+}
+
+func (c *Credential) IsEmpty() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+type Repo struct {
+	Id    string
+	Owner string
+	Name  string
+}
+
+// RepoLister is implemented by the platform which can list all its repos.
+type RepoLister interface {
+	// ListRepos returns the repos of page which starts from 1,
+	// and whether there are more pages.
+	ListRepos(page int) ([]Repo, bool, error)
+}
+
+// RepoSizer is implemented by the platform which can tell the size of repo.
+type RepoSizer interface {
+	// GetRepoSize returns the bytes of git objects of repo, excluding
+	// the lfs files. It returns 0 if the size is unknown.
+	GetRepoSize(repo *Repo) (int64, error)
 }
@@ -0,0 +1,26 @@
+package domain
+
+// RetryTask records a failed event which will be retried later.
+type RetryTask struct {
+	Id string
+
+	// EventId is the id of the original event.
+	EventId string
+
+	// Topic is the topic which the event is received from.
+	Topic   string
+	Header  map[string]string
+	Payload []byte
+
+	Attempts  int
+	LastError string
+
+	// NextRetryAt is unix time in seconds.
+	NextRetryAt int64
+
+	// Quarantined means the task has run out of attempts and
+	// will not be retried until it is replayed by operators.
+	Quarantined bool
+
+	Version int
+}
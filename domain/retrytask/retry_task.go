@@ -0,0 +1,43 @@
+package retrytask
+
+import (
+	"github.com/opensourceways/xihe-sync-repo/domain"
+)
+
+// errorTaskNotExists
+type errorTaskNotExists struct {
+	error
+}
+
+func NewErrorTaskNotExists(err error) errorTaskNotExists {
+	return errorTaskNotExists{err}
+}
+
+func IsErrorTaskNotExists(err error) bool {
+	_, ok := err.(errorTaskNotExists)
+
+	return ok
+}
+
+// errorConcurrentUpdating
+type errorConcurrentUpdating struct {
+	error
+}
+
+func NewErrorConcurrentUpdating(err error) errorConcurrentUpdating {
+	return errorConcurrentUpdating{err}
+}
+
+func IsErrorConcurrentUpdating(err error) bool {
+	_, ok := err.(errorConcurrentUpdating)
+
+	return ok
+}
+
+type RetryTaskRepo interface {
+	Save(*domain.RetryTask) (domain.RetryTask, error)
+	Find(id string) (domain.RetryTask, error)
+	FindDue(now int64, limit int) ([]domain.RetryTask, error)
+	FindQuarantined() ([]domain.RetryTask, error)
+	Delete(id string) error
+}
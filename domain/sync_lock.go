@@ -3,19 +3,26 @@ package domain
 import "errors"
 
 const (
-	repoSyncStatusDone    = "done"
-	repoSyncStatusRunning = "running"
+	repoSyncStatusDone      = "done"
+	repoSyncStatusRunning   = "running"
+	repoSyncStatusDestroyed = "destroyed"
 )
 
 var (
 	RepoSyncStatusDone    = repoSyncStatus(repoSyncStatusDone)
 	RepoSyncStatusRunning = repoSyncStatus(repoSyncStatusRunning)
+
+	// RepoSyncStatusDestroyed is the tombstone of repo which has been
+	// destroyed on the platform and purged from the obs.
+	RepoSyncStatusDestroyed = repoSyncStatus(repoSyncStatusDestroyed)
 )
 
 // RepoSyncStatus
 type RepoSyncStatus interface {
 	RepoSyncStatus() string
 	IsDone() bool
+	IsRunning() bool
+	IsDestroyed() bool
 }
 
 func NewRepoSyncStatus(s string) (RepoSyncStatus, error) {
@@ -23,7 +30,8 @@ func NewRepoSyncStatus(s string) (RepoSyncStatus, error) {
 		return nil, nil
 	}
 
-	if s != repoSyncStatusDone && s != repoSyncStatusRunning {
+	if s != repoSyncStatusDone && s != repoSyncStatusRunning &&
+		s != repoSyncStatusDestroyed {
 		return nil, errors.New("invalid repo sync status")
 	}
 
@@ -40,11 +48,52 @@ func (s repoSyncStatus) IsDone() bool {
 	return string(s) == repoSyncStatusDone
 }
 
+func (s repoSyncStatus) IsRunning() bool {
+	return string(s) == repoSyncStatusRunning
+}
+
+func (s repoSyncStatus) IsDestroyed() bool {
+	return string(s) == repoSyncStatusDestroyed
+}
+
 type RepoSyncLock struct {
-	Id         string
-	Owner      Account
-	RepoId     string
+	Id     string
+	Owner  Account
+	RepoId string
+
+	// Platform is the platform which the repo is hosted on. It is empty
+	// for gitlab, and the repo is identified by it with Owner and RepoId.
+	Platform string
+
 	Status     RepoSyncStatus
 	Version    int
 	LastCommit string
+
+	// Branch is the branch which LastCommit belongs to.
+	Branch string
+
+	// Holder is the instance which is running the sync.
+	Holder string
+
+	// StartedAt and HeartbeatAt are unix time in seconds.
+	StartedAt   int64
+	HeartbeatAt int64
+
+	// NonFastForwardAt is the unix time when the last non-fast-forward
+	// push, such as force-push, was synced.
+	NonFastForwardAt int64
+}
+
+func (r *RepoSyncLock) IsRunning() bool {
+	return r.Status != nil && r.Status.IsRunning()
+}
+
+func (r *RepoSyncLock) IsDestroyed() bool {
+	return r.Status != nil && r.Status.IsDestroyed()
+}
+
+// IsExpired checks whether the holder has not renewed the lock
+// within the lease. The lock without heartbeat is regarded as expired.
+func (r *RepoSyncLock) IsExpired(now, lease int64) bool {
+	return r.HeartbeatAt+lease < now
 }
@@ -0,0 +1,119 @@
+package syncengine
+
+import (
+	"context"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+type LFSFile struct {
+	Path string
+	SHA  string
+	Size int64
+}
+
+// TreeFile is a regular file of the tree of commit.
+type TreeFile struct {
+	Path string
+	Size int64
+
+	// LFS is not nil if the file is a lfs pointer.
+	LFS *LFSFile
+}
+
+type TreeOption struct {
+	CloneURL   string
+	Credential platform.Credential
+
+	// RepoKey identifies the repo, same as SyncOption.RepoKey.
+	RepoKey string
+
+	Commit string
+}
+
+type UploadOption struct {
+	// WorkDir is the directory where the files are extracted to.
+	WorkDir string
+
+	CloneURL   string
+	Credential platform.Credential
+	RepoKey    string
+	Commit     string
+	OBSPath    string
+
+	// Files are the small files of the tree of commit to be uploaded.
+	Files []string
+}
+
+type SyncOption struct {
+	// WorkDir is the directory where the repo will be cloned to.
+	WorkDir string
+
+	CloneURL   string
+	Credential platform.Credential
+
+	// RepoKey identifies the repo, such as owner/repo_id.
+	// It is used to find the cached mirror of repo.
+	RepoKey string
+
+	// Ref is the branch or tag to be synced.
+	// It is the default branch if empty.
+	Ref string
+
+	// StartCommit is the commit synced last time. It syncs all the files
+	// of repo if it is empty.
+	StartCommit string
+
+	// OBSPath is the obs path which the files of repo will be saved to.
+	OBSPath string
+
+	// ReservedPaths are the paths under OBSPath which are not the files
+	// of repo. They will not be synced or deleted when reconciling.
+	ReservedPaths []string
+}
+
+type SyncResult struct {
+	LastCommit string
+
+	// Branch is the branch which has been synced.
+	// It is empty if a tag is synced.
+	Branch string
+
+	// Added and Modified are the small files which have been uploaded.
+	Added    []string
+	Modified []string
+	Deleted  []string
+
+	// LFSFiles are the lfs files which should be copied from the lfs store.
+	LFSFiles []LFSFile
+
+	UploadedBytes int64
+	LFSBytes      int64
+
+	// NonFastForward means the start commit is missing or is not
+	// the ancestor of last commit, and all the files have been
+	// reconciled.
+	NonFastForward bool
+}
+
+func (r *SyncResult) HasLFSFiles() bool {
+	return len(r.LFSFiles) > 0
+}
+
+// SyncEngine stops writing to obs once the ctx is done,
+// for example the lock of repo is lost.
+type SyncEngine interface {
+	Sync(context.Context, *SyncOption) (SyncResult, error)
+
+	// ListTree lists the regular files of the tree of commit.
+	// The symlinks and submodules are ignored, same as Sync.
+	ListTree(*TreeOption) ([]TreeFile, error)
+
+	// UploadFiles uploads the files of the tree of commit and returns
+	// the uploaded bytes.
+	UploadFiles(context.Context, *UploadOption) (int64, error)
+
+	// EvictMirror deletes the cached mirror of repo whose key will not
+	// be used any more, for example the repo is destroyed.
+	EvictMirror(repoKey string) error
+}
@@ -18,7 +18,26 @@ func IsRepoSyncLockNotExist(err error) bool {
 	return ok
 }
 
+// errorConcurrentUpdating
+type errorConcurrentUpdating struct {
+	error
+}
+
+func NewErrorConcurrentUpdating(err error) errorConcurrentUpdating {
+	return errorConcurrentUpdating{err}
+}
+
+func IsErrorConcurrentUpdating(err error) bool {
+	_, ok := err.(errorConcurrentUpdating)
+
+	return ok
+}
+
 type RepoSyncLock interface {
-	Find(owner domain.Account, repoId string) (domain.RepoSyncLock, error)
+	// Find returns the lock of repo. The platform is empty for gitlab.
+	Find(owner domain.Account, platform, repoId string) (domain.RepoSyncLock, error)
 	Save(*domain.RepoSyncLock) (domain.RepoSyncLock, error)
+
+	// ChangeOwner saves the lock and moves it to the new owner.
+	ChangeOwner(*domain.RepoSyncLock, domain.Account) (domain.RepoSyncLock, error)
 }
@@ -0,0 +1,71 @@
+package dedupimpl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/dedup"
+)
+
+type EventMapper interface {
+	// Exists checks whether the event exists and has not expired before now.
+	Exists(eventId string, now int64) (bool, error)
+
+	// Save inserts the event, or updates its expiry if it exists.
+	Save(do *EventDO) error
+
+	DeleteExpired(now int64) error
+}
+
+// NewEventDedup returns the dedup which keeps the events for ttl seconds
+// by mapper. It is shared by all the instances.
+func NewEventDedup(mapper EventMapper, ttl int64) dedup.EventDedup {
+	return &eventDedup{
+		ttl:    ttl,
+		mapper: mapper,
+	}
+}
+
+type eventDedup struct {
+	ttl    int64
+	mapper EventMapper
+
+	// the expired events are deleted once per ttl.
+	lock      sync.Mutex
+	cleanedAt int64
+}
+
+func (impl *eventDedup) IsHandled(eventId string) (bool, error) {
+	return impl.mapper.Exists(eventId, time.Now().Unix())
+}
+
+func (impl *eventDedup) MarkHandled(eventId string) error {
+	now := time.Now().Unix()
+
+	impl.clean(now)
+
+	return impl.mapper.Save(&EventDO{
+		EventId:  eventId,
+		ExpireAt: now + impl.ttl,
+	})
+}
+
+func (impl *eventDedup) clean(now int64) {
+	impl.lock.Lock()
+	if now-impl.cleanedAt < impl.ttl {
+		impl.lock.Unlock()
+
+		return
+	}
+
+	impl.cleanedAt = now
+	impl.lock.Unlock()
+
+	// it will be done next time if failed.
+	_ = impl.mapper.DeleteExpired(now)
+}
+
+type EventDO struct {
+	EventId  string
+	ExpireAt int64
+}
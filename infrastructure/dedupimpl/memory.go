@@ -0,0 +1,90 @@
+package dedupimpl
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/dedup"
+)
+
+// NewMemoryEventDedup returns the dedup which keeps at most size events
+// for ttl seconds in memory. It only drops the duplicate events delivered
+// to the same instance.
+func NewMemoryEventDedup(ttl int64, size int) dedup.EventDedup {
+	return &memoryDedup{
+		ttl:    ttl,
+		size:   size,
+		events: map[string]*list.Element{},
+		order:  list.New(),
+	}
+}
+
+type memoryRecord struct {
+	eventId  string
+	expireAt int64
+}
+
+type memoryDedup struct {
+	ttl  int64
+	size int
+
+	lock   sync.Mutex
+	events map[string]*list.Element
+
+	// order keeps the records by the time they are added, which is
+	// the order they expire too, since the ttl is the same.
+	order *list.List
+}
+
+func (impl *memoryDedup) IsHandled(eventId string) (bool, error) {
+	now := time.Now().Unix()
+
+	impl.lock.Lock()
+	defer impl.lock.Unlock()
+
+	impl.evict(func(r *memoryRecord) bool { return r.expireAt <= now })
+
+	_, ok := impl.events[eventId]
+
+	return ok, nil
+}
+
+func (impl *memoryDedup) MarkHandled(eventId string) error {
+	now := time.Now().Unix()
+
+	impl.lock.Lock()
+	defer impl.lock.Unlock()
+
+	impl.evict(func(r *memoryRecord) bool { return r.expireAt <= now })
+
+	// renew it by adding again, so that the order is kept.
+	if e, ok := impl.events[eventId]; ok {
+		impl.order.Remove(e)
+		delete(impl.events, eventId)
+	}
+
+	// make room for the new one.
+	impl.evict(func(*memoryRecord) bool { return impl.order.Len() >= impl.size })
+
+	impl.events[eventId] = impl.order.PushBack(&memoryRecord{
+		eventId:  eventId,
+		expireAt: now + impl.ttl,
+	})
+
+	return nil
+}
+
+// evict removes the oldest records until the oldest one is not needed
+// to be removed.
+func (impl *memoryDedup) evict(needed func(*memoryRecord) bool) {
+	for e := impl.order.Front(); e != nil; e = impl.order.Front() {
+		r := e.Value.(*memoryRecord)
+		if !needed(r) {
+			return
+		}
+
+		impl.order.Remove(e)
+		delete(impl.events, r.eventId)
+	}
+}
@@ -0,0 +1,40 @@
+package electionimpl
+
+import (
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/election"
+)
+
+type LeaderLeaseMapper interface {
+	// Acquire updates the holder and expiry of lease if it is held by
+	// holder or has expired before now, and returns whether it succeeds.
+	Acquire(do *LeaderLeaseDO, now int64) (bool, error)
+}
+
+func NewElector(mapper LeaderLeaseMapper) election.Elector {
+	return elector{mapper}
+}
+
+type elector struct {
+	mapper LeaderLeaseMapper
+}
+
+func (impl elector) TryAcquire(name, holder string, lease int64) (bool, error) {
+	now := time.Now().Unix()
+
+	return impl.mapper.Acquire(
+		&LeaderLeaseDO{
+			Name:     name,
+			Holder:   holder,
+			ExpireAt: now + lease,
+		},
+		now,
+	)
+}
+
+type LeaderLeaseDO struct {
+	Name     string
+	Holder   string
+	ExpireAt int64
+}
@@ -0,0 +1,19 @@
+package localobsimpl
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+type Config struct {
+	// RootDir is the directory which the objects are saved in.
+	RootDir string `json:"root_dir" required:"true"`
+}
+
+func (c *Config) Validate() error {
+	if !filepath.IsAbs(c.RootDir) {
+		return errors.New("root_dir must be an absolute path")
+	}
+
+	return nil
+}
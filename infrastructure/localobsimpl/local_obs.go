@@ -0,0 +1,156 @@
+package localobsimpl
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/obs"
+)
+
+const tempFilePrefix = ".tmp-"
+
+// NewOBS returns an implementation of obs which saves the objects in
+// a directory tree. It is used for local development and tests.
+func NewOBS(cfg *Config) (obs.OBS, error) {
+	if err := os.MkdirAll(cfg.RootDir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &localOBS{
+		root: cfg.RootDir,
+	}, nil
+}
+
+type localOBS struct {
+	root string
+}
+
+// toFile converts the object path to the file path which is always
+// under the root directory.
+func (s *localOBS) toFile(path string) string {
+	return filepath.Join(s.root, filepath.Clean("/"+path))
+}
+
+func (s *localOBS) SaveObject(path, content string) error {
+	return s.write(s.toFile(path), func(f *os.File) error {
+		_, err := f.WriteString(content)
+
+		return err
+	})
+}
+
+func (s *localOBS) SaveFile(path, localFile string) error {
+	return s.copy(s.toFile(path), localFile)
+}
+
+func (s *localOBS) GetObject(path string) ([]byte, error) {
+	v, err := ioutil.ReadFile(s.toFile(path))
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return v, err
+}
+
+func (s *localOBS) CopyObject(dst, src string) error {
+	return s.copy(s.toFile(dst), s.toFile(src))
+}
+
+func (s *localOBS) DeleteObject(path string) error {
+	err := os.Remove(s.toFile(path))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *localOBS) ListObjects(prefix string) ([]obs.ObjectInfo, error) {
+	// the prefix may be a part of file name, so walk from its parent.
+	dir := s.toFile(prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		dir = filepath.Dir(dir)
+	}
+
+	var r []obs.ObjectInfo
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, file)
+		if err != nil {
+			return err
+		}
+
+		if p := filepath.ToSlash(rel); strings.HasPrefix(p, prefix) {
+			r = append(r, obs.ObjectInfo{
+				Path: p,
+				Size: info.Size(),
+			})
+		}
+
+		return nil
+	})
+
+	return r, err
+}
+
+func (s *localOBS) copy(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return s.write(dst, func(f *os.File) error {
+		_, err := io.Copy(f, in)
+
+		return err
+	})
+}
+
+// write writes the file atomically by renaming a temporary file to it,
+// so that the readers will never see a partial object.
+func (s *localOBS) write(file string, handle func(*os.File) error) error {
+	dir := filepath.Dir(file)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, tempFilePrefix)
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+
+	if err = handle(f); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+	}
+
+	return err
+}
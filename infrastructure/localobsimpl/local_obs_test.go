@@ -0,0 +1,75 @@
+package localobsimpl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/obs/obstest"
+)
+
+func TestLocalOBS(t *testing.T) {
+	s, err := NewOBS(&Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obstest.TestOBS(t, s)
+}
+
+func TestPathOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+
+	s, err := NewOBS(&Config{RootDir: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SaveObject("../outside", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Errorf("the object is saved outside of root, err:%v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "outside")); err != nil {
+		t.Errorf("the object is not saved under root, err:%v", err)
+	}
+}
+
+func TestTempFileIsNotListed(t *testing.T) {
+	root := t.TempDir()
+
+	s, err := NewOBS(&Config{RootDir: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SaveObject("dir/file", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the temporary file left by a crashed writing.
+	tmp := filepath.Join(root, "dir", tempFilePrefix+"123")
+	if err := os.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	objs, err := s.ListObjects("dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range objs {
+		if strings.Contains(item.Path, tempFilePrefix) {
+			t.Errorf("the temporary file %s is listed", item.Path)
+		}
+	}
+
+	if len(objs) != 1 {
+		t.Errorf("got %v, want dir/file only", objs)
+	}
+}
@@ -1,5 +1,7 @@
 package mysql
 
+import driver "github.com/go-sql-driver/mysql"
+
 type Config struct {
 	Conn            string `json:"conn" required:"true"`
 	ConnMaxLifetime int    `json:"conn_max_life_time"`
@@ -7,10 +9,37 @@ type Config struct {
 	MaxIdleConns    int    `json:"max_idle_conns"`
 
 	TableName string `json:"table_name"   required:"true"`
+
+	// RetryTableName is retry_task by default.
+	RetryTableName string `json:"retry_table_name"`
+
+	// LeaderTableName is leader_lease by default.
+	LeaderTableName string `json:"leader_table_name"`
+
+	// DedupTableName is required if the events are deduplicated by mysql.
+	DedupTableName string `json:"dedup_table_name"`
 }
 
 func (cfg *Config) SetDefault() {
 	cfg.ConnMaxLifetime = 900
 	cfg.MaxOpenConns = 3000
 	cfg.MaxIdleConns = 30
+
+	if cfg.RetryTableName == "" {
+		cfg.RetryTableName = "retry_task"
+	}
+
+	if cfg.LeaderTableName == "" {
+		cfg.LeaderTableName = "leader_lease"
+	}
+}
+
+// Password returns the password in the connection.
+func (cfg *Config) Password() string {
+	v, err := driver.ParseDSN(cfg.Conn)
+	if err != nil {
+		return ""
+	}
+
+	return v.Passwd
 }
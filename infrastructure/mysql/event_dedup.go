@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"gorm.io/gorm/clause"
+
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/dedupimpl"
+)
+
+func NewEventMapper() dedupimpl.EventMapper {
+	return eventRecord{}
+}
+
+type eventRecord struct{}
+
+func (er eventRecord) Exists(eventId string, now int64) (bool, error) {
+	var n int64
+
+	err := cli.db.Model(&EventRecord{}).
+		Where(fieldEventId+" = ?", eventId).
+		Where(fieldExpireAt+" > ?", now).
+		Count(&n).Error
+
+	return n > 0, err
+}
+
+func (er eventRecord) Save(do *dedupimpl.EventDO) error {
+	table := EventRecord{
+		EventId:  do.EventId,
+		ExpireAt: do.ExpireAt,
+	}
+
+	return cli.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{fieldExpireAt}),
+	}).Create(&table).Error
+}
+
+func (er eventRecord) DeleteExpired(now int64) error {
+	return cli.db.Where(fieldExpireAt+" <= ?", now).Delete(&EventRecord{}).Error
+}
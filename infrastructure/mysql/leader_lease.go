@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"gorm.io/gorm/clause"
+
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/electionimpl"
+)
+
+func NewLeaderLeaseMapper() electionimpl.LeaderLeaseMapper {
+	return leaderLease{}
+}
+
+type leaderLease struct{}
+
+func (ll leaderLease) Acquire(do *electionimpl.LeaderLeaseDO, now int64) (bool, error) {
+	table := LeaderLease{
+		Name:     do.Name,
+		Holder:   do.Holder,
+		ExpireAt: do.ExpireAt,
+	}
+
+	// create the lease if it does not exist.
+	r := cli.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&table)
+	if r.Error != nil {
+		return false, r.Error
+	}
+
+	if r.RowsAffected == 1 {
+		return true, nil
+	}
+
+	tx := cli.db.Model(&LeaderLease{}).
+		Where(fieldName+" = ?", do.Name).
+		Where(fieldHolder+" = ? OR "+fieldExpireAt+" < ?", do.Holder, now).
+		Updates(map[string]interface{}{
+			fieldHolder:   do.Holder,
+			fieldExpireAt: do.ExpireAt,
+		})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	if tx.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// mysql reports 0 affected row if the values are not changed,
+	// for example the holder renews it within the same second.
+	data := new(LeaderLease)
+	if err := cli.db.Where(fieldName+" = ?", do.Name).First(data).Error; err != nil {
+		return false, err
+	}
+
+	return data.Holder == do.Holder && data.ExpireAt == do.ExpireAt, nil
+}
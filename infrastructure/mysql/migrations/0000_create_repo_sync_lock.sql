@@ -0,0 +1,12 @@
+-- The table of sync lock before the lease of running lock was added.
+-- Apply it on a new database only.
+CREATE TABLE IF NOT EXISTS `{table_name}` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `owner` VARCHAR(255) NOT NULL,
+  `repo_id` VARCHAR(255) NOT NULL,
+  `status` VARCHAR(32) NOT NULL DEFAULT '',
+  `version` INT NOT NULL DEFAULT 0,
+  `last_commit` VARCHAR(64) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_owner_repo_id` (`owner`, `repo_id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
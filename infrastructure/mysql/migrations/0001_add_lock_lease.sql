@@ -0,0 +1,6 @@
+-- The holder and the heartbeat of running lock, so that the stale
+-- running lock can be recovered. 0 means the lock is not running.
+ALTER TABLE `{table_name}`
+  ADD COLUMN `holder` VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN `started_at` BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN `heartbeat_at` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+-- The delayed retries of the events which failed to be handled.
+CREATE TABLE IF NOT EXISTS `{retry_table_name}` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `event_id` VARCHAR(255) NOT NULL DEFAULT '',
+  `header` TEXT NOT NULL,
+  `payload` MEDIUMTEXT NOT NULL,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `last_error` TEXT NOT NULL,
+  `next_retry_at` BIGINT NOT NULL DEFAULT 0,
+  `quarantined` BOOLEAN NOT NULL DEFAULT FALSE,
+  `version` INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_quarantined_next_retry_at` (`quarantined`, `next_retry_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,4 @@
+-- The time when the last non-fast-forward push was detected.
+-- 0 means it has not happened.
+ALTER TABLE `{table_name}`
+  ADD COLUMN `non_fast_forward_at` BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- The lease of leader which runs the periodic reconciliation.
+CREATE TABLE IF NOT EXISTS `{leader_table_name}` (
+  `name` VARCHAR(255) NOT NULL,
+  `holder` VARCHAR(255) NOT NULL DEFAULT '',
+  `expire_at` BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,4 @@
+-- The branch which last_commit belongs to. It is empty for the repos
+-- which have not been synced since the branch was recorded.
+ALTER TABLE `{table_name}`
+  ADD COLUMN `branch` VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- The platform of repo which is empty for gitlab. The default value
+-- must be '', otherwise the existing locks of gitlab will not be
+-- found and their repos will be synced from scratch.
+ALTER TABLE `{table_name}`
+  ADD COLUMN `platform` VARCHAR(32) NOT NULL DEFAULT '',
+  DROP INDEX `uk_owner_repo_id`,
+  ADD UNIQUE KEY `uk_owner_platform_repo_id` (`owner`, `platform`, `repo_id`);
+
+-- The topic which the event of retry task is received from.
+-- It is empty for the tasks created before it was recorded.
+ALTER TABLE `{retry_table_name}`
+  ADD COLUMN `topic` VARCHAR(255) NOT NULL DEFAULT '';
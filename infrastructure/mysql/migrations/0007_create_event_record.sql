@@ -0,0 +1,8 @@
+-- The handled events which are deduplicated by mysql.
+-- It is needed only if syncrepo.dedup.store is mysql.
+CREATE TABLE IF NOT EXISTS `{dedup_table_name}` (
+  `event_id` VARCHAR(255) NOT NULL,
+  `expire_at` BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`event_id`),
+  KEY `idx_expire_at` (`expire_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
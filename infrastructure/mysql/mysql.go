@@ -37,6 +37,9 @@ func Init(cfg *Config) error {
 	}
 
 	tableName = cfg.TableName
+	retryTableName = cfg.RetryTableName
+	leaderTableName = cfg.LeaderTableName
+	dedupTableName = cfg.DedupTableName
 
 	return nil
 }
@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/retrytaskimpl"
+)
+
+func NewRetryTaskMapper() retrytaskimpl.RetryTaskMapper {
+	return retryTask{}
+}
+
+type retryTask struct{}
+
+func (rt retryTask) Insert(do *retrytaskimpl.RetryTaskDO) (string, error) {
+	table := rt.toRetryTaskTable(do)
+
+	if err := cli.db.Model(&table).Create(&table).Error; err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(table.Id), nil
+}
+
+func (rt retryTask) Get(id string) (do retrytaskimpl.RetryTaskDO, err error) {
+	v, err := strconv.Atoi(id)
+	if err != nil {
+		err = retrytaskimpl.NewErrorDataNotExists(err)
+
+		return
+	}
+
+	data := new(RetryTask)
+
+	err = cli.db.Model(data).Where(&RetryTask{Id: v}).First(data).Error
+
+	if err == nil {
+		do = rt.toRetryTaskDO(data)
+	} else {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = retrytaskimpl.NewErrorDataNotExists(err)
+		}
+	}
+
+	return
+}
+
+func (rt retryTask) ListDue(now int64, limit int) ([]retrytaskimpl.RetryTaskDO, error) {
+	var data []RetryTask
+
+	err := cli.db.Model(&RetryTask{}).
+		Where(fieldQuarantined+" = ?", false).
+		Where(fieldNextRetryAt+" <= ?", now).
+		Order(fieldNextRetryAt).
+		Limit(limit).
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.toRetryTaskDOs(data), nil
+}
+
+func (rt retryTask) ListQuarantined() ([]retrytaskimpl.RetryTaskDO, error) {
+	var data []RetryTask
+
+	err := cli.db.Model(&RetryTask{}).
+		Where(fieldQuarantined+" = ?", true).
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rt.toRetryTaskDOs(data), nil
+}
+
+func (rt retryTask) Update(do *retrytaskimpl.RetryTaskDO) error {
+	id, err := strconv.Atoi(do.Id)
+	if err != nil {
+		return retrytaskimpl.NewErrorDataNotExists(err)
+	}
+
+	// use map as the condition, otherwise the zero version will be ignored.
+	cond := map[string]interface{}{
+		fieldId:      id,
+		fieldVersion: do.Version,
+	}
+
+	tx := cli.db.Model(&RetryTask{}).Where(cond).Updates(
+		map[string]interface{}{
+			fieldVersion:     gorm.Expr(fieldVersion+" + ?", 1),
+			fieldAttempts:    do.Attempts,
+			fieldLastError:   do.LastError,
+			fieldNextRetryAt: do.NextRetryAt,
+			fieldQuarantined: do.Quarantined,
+		},
+	)
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return retrytaskimpl.NewErrorConcurrentUpdating(
+			errors.New("no matched record"),
+		)
+	}
+
+	return nil
+}
+
+func (rt retryTask) Delete(id string) error {
+	v, err := strconv.Atoi(id)
+	if err != nil {
+		return retrytaskimpl.NewErrorDataNotExists(err)
+	}
+
+	return cli.db.Delete(&RetryTask{Id: v}).Error
+}
+
+func (rt retryTask) toRetryTaskTable(do *retrytaskimpl.RetryTaskDO) RetryTask {
+	return RetryTask{
+		EventId:     do.EventId,
+		Topic:       do.Topic,
+		Header:      do.Header,
+		Payload:     do.Payload,
+		Attempts:    do.Attempts,
+		LastError:   do.LastError,
+		NextRetryAt: do.NextRetryAt,
+		Quarantined: do.Quarantined,
+		Version:     do.Version,
+	}
+}
+
+func (rt retryTask) toRetryTaskDO(data *RetryTask) retrytaskimpl.RetryTaskDO {
+	return retrytaskimpl.RetryTaskDO{
+		Id:          strconv.Itoa(data.Id),
+		EventId:     data.EventId,
+		Topic:       data.Topic,
+		Header:      data.Header,
+		Payload:     data.Payload,
+		Attempts:    data.Attempts,
+		LastError:   data.LastError,
+		NextRetryAt: data.NextRetryAt,
+		Quarantined: data.Quarantined,
+		Version:     data.Version,
+	}
+}
+
+func (rt retryTask) toRetryTaskDOs(data []RetryTask) []retrytaskimpl.RetryTaskDO {
+	r := make([]retrytaskimpl.RetryTaskDO, len(data))
+
+	for i := range data {
+		r[i] = rt.toRetryTaskDO(&data[i])
+	}
+
+	return r
+}
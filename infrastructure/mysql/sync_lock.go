@@ -32,15 +32,12 @@ func (rs syncLock) Insert(do *synclockimpl.RepoSyncLockDO) (string, error) {
 	return strconv.Itoa(table.Id), nil
 }
 
-func (rs syncLock) Get(owner, repoId string) (do synclockimpl.RepoSyncLockDO, err error) {
-	cond := &RepoSyncLock{
-		Owner:  owner,
-		RepoId: repoId,
-	}
-
+func (rs syncLock) Get(owner, platform, repoId string) (
+	do synclockimpl.RepoSyncLockDO, err error,
+) {
 	data := new(RepoSyncLock)
 
-	err = cli.db.Model(data).Where(cond).First(data).Error
+	err = cli.db.Model(data).Where(rs.keyOf(owner, platform, repoId)).First(data).Error
 
 	if err == nil {
 		do = rs.toSyncLockDo(data)
@@ -54,19 +51,37 @@ func (rs syncLock) Get(owner, repoId string) (do synclockimpl.RepoSyncLockDO, er
 }
 
 func (rs syncLock) Update(do *synclockimpl.RepoSyncLockDO) error {
-	cond := &RepoSyncLock{
-		Owner:   do.Owner,
-		RepoId:  do.RepoId,
-		Version: do.Version,
+	return rs.update(do, rs.toUpdatedFields(do))
+}
+
+func (rs syncLock) UpdateOwner(do *synclockimpl.RepoSyncLockDO, owner string) error {
+	v := rs.toUpdatedFields(do)
+	v[fieldOwner] = owner
+
+	return rs.update(do, v)
+}
+
+func (rs syncLock) toUpdatedFields(do *synclockimpl.RepoSyncLockDO) map[string]interface{} {
+	return map[string]interface{}{
+		fieldVersion:     gorm.Expr(fieldVersion+" + ?", 1),
+		fieldLastCommit:  do.LastCommit,
+		fieldBranch:      do.Branch,
+		fieldStatus:      do.Status,
+		fieldHolder:      do.Holder,
+		fieldStartedAt:   do.StartedAt,
+		fieldHeartbeatAt: do.HeartbeatAt,
+
+		fieldNonFastForwardAt: do.NonFastForwardAt,
 	}
+}
 
-	tx := cli.db.Model(cond).Where(cond).Updates(
-		map[string]interface{}{
-			fieldVersion:    gorm.Expr(fieldVersion+" + ?", 1),
-			fieldLastCommit: do.LastCommit,
-			fieldStatus:     do.Status,
-		},
-	)
+func (rs syncLock) update(
+	do *synclockimpl.RepoSyncLockDO, fields map[string]interface{},
+) error {
+	cond := rs.keyOf(do.Owner, do.Platform, do.RepoId)
+	cond[fieldVersion] = do.Version
+
+	tx := cli.db.Model(&RepoSyncLock{}).Where(cond).Updates(fields)
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -80,13 +95,31 @@ func (rs syncLock) Update(do *synclockimpl.RepoSyncLockDO) error {
 	return nil
 }
 
+// keyOf returns the condition of repo. The map is used instead of the struct,
+// because the empty platform of gitlab will be ignored by the struct.
+func (rs syncLock) keyOf(owner, platform, repoId string) map[string]interface{} {
+	return map[string]interface{}{
+		fieldOwner:    owner,
+		fieldRepoId:   repoId,
+		fieldPlatform: platform,
+	}
+}
+
 func (rs syncLock) toSyncLockTable(do *synclockimpl.RepoSyncLockDO) RepoSyncLock {
 	return RepoSyncLock{
 		Owner:      do.Owner,
 		RepoId:     do.RepoId,
+		Platform:   do.Platform,
 		Status:     do.Status,
 		Version:    do.Version,
 		LastCommit: do.LastCommit,
+		Branch:     do.Branch,
+
+		Holder:      do.Holder,
+		StartedAt:   do.StartedAt,
+		HeartbeatAt: do.HeartbeatAt,
+
+		NonFastForwardAt: do.NonFastForwardAt,
 	}
 }
 
@@ -95,8 +128,16 @@ func (rs syncLock) toSyncLockDo(data *RepoSyncLock) synclockimpl.RepoSyncLockDO
 		Id:         strconv.Itoa(data.Id),
 		Owner:      data.Owner,
 		RepoId:     data.RepoId,
+		Platform:   data.Platform,
 		Status:     data.Status,
 		Version:    data.Version,
 		LastCommit: data.LastCommit,
+		Branch:     data.Branch,
+
+		Holder:      data.Holder,
+		StartedAt:   data.StartedAt,
+		HeartbeatAt: data.HeartbeatAt,
+
+		NonFastForwardAt: data.NonFastForwardAt,
 	}
 }
@@ -1,22 +1,95 @@
 package mysql
 
 const (
-	fieldStatus     = "status"
-	fieldVersion    = "version"
-	fieldLastCommit = "last_commit"
+	fieldOwner       = "owner"
+	fieldRepoId      = "repo_id"
+	fieldPlatform    = "platform"
+	fieldHolder      = "holder"
+	fieldStatus      = "status"
+	fieldVersion     = "version"
+	fieldStartedAt   = "started_at"
+	fieldLastCommit  = "last_commit"
+	fieldBranch      = "branch"
+	fieldHeartbeatAt = "heartbeat_at"
+
+	fieldNonFastForwardAt = "non_fast_forward_at"
 )
 
-var tableName = ""
+var (
+	tableName       = ""
+	retryTableName  = ""
+	leaderTableName = ""
+	dedupTableName  = ""
+)
 
 type RepoSyncLock struct {
 	Id         int    `json:"-"            gorm:"column:id"`
 	Owner      string `json:"-"            gorm:"column:owner"`
 	RepoId     string `json:"-"            gorm:"column:repo_id"`
+	Platform   string `json:"-"            gorm:"column:platform"`
 	Status     string `json:"status"       gorm:"column:status"`
 	Version    int    `json:"-"            gorm:"column:version"`
 	LastCommit string `json:"last_commit"  gorm:"column:last_commit"`
+	Branch     string `json:"branch"       gorm:"column:branch"`
+
+	Holder      string `json:"holder"       gorm:"column:holder"`
+	StartedAt   int64  `json:"started_at"   gorm:"column:started_at"`
+	HeartbeatAt int64  `json:"heartbeat_at" gorm:"column:heartbeat_at"`
+
+	NonFastForwardAt int64 `json:"non_fast_forward_at" gorm:"column:non_fast_forward_at"`
 }
 
 func (r *RepoSyncLock) TableName() string {
 	return tableName
 }
+
+const (
+	fieldId          = "id"
+	fieldAttempts    = "attempts"
+	fieldLastError   = "last_error"
+	fieldNextRetryAt = "next_retry_at"
+	fieldQuarantined = "quarantined"
+)
+
+type RetryTask struct {
+	Id          int    `json:"-"             gorm:"column:id"`
+	EventId     string `json:"event_id"      gorm:"column:event_id"`
+	Topic       string `json:"topic"         gorm:"column:topic"`
+	Header      string `json:"header"        gorm:"column:header"`
+	Payload     string `json:"payload"       gorm:"column:payload"`
+	Attempts    int    `json:"attempts"      gorm:"column:attempts"`
+	LastError   string `json:"last_error"    gorm:"column:last_error"`
+	NextRetryAt int64  `json:"next_retry_at" gorm:"column:next_retry_at"`
+	Quarantined bool   `json:"quarantined"   gorm:"column:quarantined"`
+	Version     int    `json:"-"             gorm:"column:version"`
+}
+
+func (r *RetryTask) TableName() string {
+	return retryTableName
+}
+
+const (
+	fieldName     = "name"
+	fieldExpireAt = "expire_at"
+)
+
+type LeaderLease struct {
+	Name     string `json:"name"       gorm:"column:name;primaryKey"`
+	Holder   string `json:"holder"     gorm:"column:holder"`
+	ExpireAt int64  `json:"expire_at"  gorm:"column:expire_at"`
+}
+
+func (r *LeaderLease) TableName() string {
+	return leaderTableName
+}
+
+const fieldEventId = "event_id"
+
+type EventRecord struct {
+	EventId  string `json:"event_id"   gorm:"column:event_id;primaryKey"`
+	ExpireAt int64  `json:"expire_at"  gorm:"column:expire_at"`
+}
+
+func (r *EventRecord) TableName() string {
+	return dedupTableName
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/sirupsen/logrus"
 
 	dobs "github.com/opensourceways/xihe-sync-repo/domain/obs"
-	"github.com/opensourceways/xihe-sync-repo/utils"
 )
 
 func NewOBS(cfg *Config) (dobs.OBS, error) {
@@ -18,25 +17,15 @@ func NewOBS(cfg *Config) (dobs.OBS, error) {
 		return nil, fmt.Errorf("new obs client failed, err:%s", err.Error())
 	}
 
-	_, err, _ = utils.RunCmd(
-		cfg.OBSUtilPath, "config",
-		"-i="+cfg.AccessKey, "-k="+cfg.SecretKey, "-e="+cfg.Endpoint,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("obsutil config failed")
-	}
-
 	return &obsImpl{
 		obsClient: cli,
 		bucket:    cfg.Bucket,
-		obsutil:   cfg.OBSUtilPath,
 	}, nil
 }
 
 type obsImpl struct {
 	obsClient *obs.ObsClient
 	bucket    string
-	obsutil   string
 }
 
 func (s *obsImpl) SaveObject(path, content string) error {
@@ -52,6 +41,17 @@ func (s *obsImpl) SaveObject(path, content string) error {
 	return err
 }
 
+func (s *obsImpl) SaveFile(path, localFile string) error {
+	input := &obs.PutFileInput{}
+	input.Bucket = s.bucket
+	input.Key = path
+	input.SourceFile = localFile
+
+	_, err := s.obsClient.PutFile(input)
+
+	return err
+}
+
 func (s *obsImpl) CopyObject(dst, src string) error {
 	input := &obs.CopyObjectInput{}
 	input.Bucket = s.bucket
@@ -88,10 +88,43 @@ func (s *obsImpl) GetObject(path string) ([]byte, error) {
 	return v, err
 }
 
-func (s *obsImpl) OBSUtilPath() string {
-	return s.obsutil
+func (s *obsImpl) DeleteObject(path string) error {
+	input := &obs.DeleteObjectInput{}
+	input.Bucket = s.bucket
+	input.Key = path
+
+	_, err := s.obsClient.DeleteObject(input)
+
+	return err
 }
 
-func (s *obsImpl) OBSBucket() string {
-	return s.bucket
+func (s *obsImpl) ListObjects(prefix string) ([]dobs.ObjectInfo, error) {
+	input := &obs.ListObjectsInput{}
+	input.Bucket = s.bucket
+	input.Prefix = prefix
+	input.MaxKeys = 1000
+
+	var r []dobs.ObjectInfo
+
+	for {
+		output, err := s.obsClient.ListObjects(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range output.Contents {
+			item := &output.Contents[i]
+
+			r = append(r, dobs.ObjectInfo{
+				Path: item.Key,
+				Size: item.Size,
+			})
+		}
+
+		if !output.IsTruncated {
+			return r, nil
+		}
+
+		input.Marker = output.NextMarker
+	}
 }
@@ -0,0 +1,126 @@
+// Package platformfake provides the fake api servers of platforms,
+// so that the platform implementations can be tested offline.
+package platformfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Repo is the repo served by the fake.
+type Repo struct {
+	// Id is used by the platforms which look up the repo by id, like github.
+	Id    string
+	Owner string
+	Name  string
+
+	DefaultBranch string
+
+	// Commits maps the branch to its last commit.
+	// The repo has no commit if Commits is empty.
+	Commits map[string]string
+}
+
+// Server is the fake api server of a platform.
+type Server struct {
+	*httptest.Server
+
+	lock  sync.RWMutex
+	repos map[string]Repo
+}
+
+func newServer(h func(*Server) http.Handler) *Server {
+	s := &Server{repos: map[string]Repo{}}
+	s.Server = httptest.NewServer(h(s))
+
+	return s
+}
+
+// SetRepo adds the repo or replaces it if it exists.
+func (s *Server) SetRepo(r Repo) {
+	s.lock.Lock()
+	s.repos[repoKey(r.Owner, r.Name)] = r
+	s.lock.Unlock()
+}
+
+func (s *Server) DeleteRepo(owner, name string) {
+	s.lock.Lock()
+	delete(s.repos, repoKey(owner, name))
+	s.lock.Unlock()
+}
+
+func (s *Server) getRepo(owner, name string) (Repo, bool) {
+	s.lock.RLock()
+	r, ok := s.repos[repoKey(owner, name)]
+	s.lock.RUnlock()
+
+	return r, ok
+}
+
+func (s *Server) getRepoById(id string) (Repo, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, r := range s.repos {
+		if r.Id == id {
+			return r, true
+		}
+	}
+
+	return Repo{}, false
+}
+
+func repoKey(owner, name string) string {
+	return owner + "/" + name
+}
+
+// parseRepoPath parses the path like prefix/repos/owner/name[/sub],
+// and sub is empty if the path is the repo itself.
+func parseRepoPath(prefix, path string) (owner, name, sub string, ok bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return
+	}
+
+	v := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if len(v) < 4 || len(v) > 5 || v[0] != "" || v[1] != "repos" {
+		return
+	}
+
+	if len(v) == 5 {
+		sub = v[4]
+	}
+
+	return v[2], v[3], sub, true
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func writeMessage(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": msg})
+}
+
+type commit struct {
+	SHA string `json:"sha"`
+}
+
+type repoInfo struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func toRepoInfo(r *Repo) repoInfo {
+	return repoInfo{
+		FullName:      repoKey(r.Owner, r.Name),
+		DefaultBranch: r.DefaultBranch,
+	}
+}
@@ -0,0 +1,61 @@
+package platformfake
+
+import (
+	"net/http"
+)
+
+const giteaAPIPrefix = "/api/v1"
+
+// NewGiteaServer returns the fake gitea which accepts the token only.
+// Its url is the host of gitea.
+func NewGiteaServer(token string) *Server {
+	return newServer(func(s *Server) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token "+token {
+				writeMessage(w, http.StatusUnauthorized, "token is required")
+
+				return
+			}
+
+			owner, name, sub, ok := parseRepoPath(giteaAPIPrefix, r.URL.Path)
+			if !ok || (sub != "" && sub != "commits") || r.Method != http.MethodGet {
+				writeMessage(w, http.StatusNotFound, "not found")
+
+				return
+			}
+
+			repo, ok := s.getRepo(owner, name)
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "repo not found")
+
+				return
+			}
+
+			if sub == "" {
+				writeJSON(w, toRepoInfo(&repo))
+
+				return
+			}
+
+			if len(repo.Commits) == 0 {
+				writeMessage(w, http.StatusConflict, "Git Repository is empty.")
+
+				return
+			}
+
+			branch := r.URL.Query().Get("sha")
+			if branch == "" {
+				branch = repo.DefaultBranch
+			}
+
+			v, ok := repo.Commits[branch]
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "sha not found")
+
+				return
+			}
+
+			writeJSON(w, []commit{{SHA: v}})
+		})
+	})
+}
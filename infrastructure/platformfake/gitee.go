@@ -0,0 +1,73 @@
+package platformfake
+
+import (
+	"net/http"
+)
+
+const giteeAPIPrefix = "/api/v5"
+
+// NewGiteeServer returns the fake gitee which accepts the token only,
+// and the token belongs to the user of login. Its url is the host of gitee.
+func NewGiteeServer(token, login string) *Server {
+	return newServer(func(s *Server) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("access_token") != token {
+				writeMessage(w, http.StatusUnauthorized, "401 Unauthorized")
+
+				return
+			}
+
+			if r.Method != http.MethodGet {
+				writeMessage(w, http.StatusNotFound, "404 Not Found")
+
+				return
+			}
+
+			if r.URL.Path == giteeAPIPrefix+"/user" {
+				writeJSON(w, map[string]string{"login": login})
+
+				return
+			}
+
+			owner, name, sub, ok := parseRepoPath(giteeAPIPrefix, r.URL.Path)
+			if !ok || (sub != "" && sub != "commits") {
+				writeMessage(w, http.StatusNotFound, "404 Not Found")
+
+				return
+			}
+
+			repo, ok := s.getRepo(owner, name)
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "404 Project Not Found")
+
+				return
+			}
+
+			if sub == "" {
+				writeJSON(w, toRepoInfo(&repo))
+
+				return
+			}
+
+			if len(repo.Commits) == 0 {
+				writeJSON(w, []commit{})
+
+				return
+			}
+
+			branch := r.URL.Query().Get("sha")
+			if branch == "" {
+				branch = repo.DefaultBranch
+			}
+
+			v, ok := repo.Commits[branch]
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "404 Not Found")
+
+				return
+			}
+
+			writeJSON(w, []commit{{SHA: v}})
+		})
+	})
+}
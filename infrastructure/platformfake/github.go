@@ -0,0 +1,75 @@
+package platformfake
+
+import (
+	"net/http"
+	"strings"
+)
+
+// NewGithubServer returns the fake github which accepts the token only.
+// Its url is the api host of github.
+func NewGithubServer(token string) *Server {
+	return newServer(func(s *Server) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				writeMessage(w, http.StatusUnauthorized, "Bad credentials")
+
+				return
+			}
+
+			id, sub, ok := parseRepositoryPath(r.URL.Path)
+			if !ok || (sub != "" && sub != "commits") || r.Method != http.MethodGet {
+				writeMessage(w, http.StatusNotFound, "Not Found")
+
+				return
+			}
+
+			repo, ok := s.getRepoById(id)
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "Not Found")
+
+				return
+			}
+
+			if sub == "" {
+				writeJSON(w, toRepoInfo(&repo))
+
+				return
+			}
+
+			if len(repo.Commits) == 0 {
+				writeMessage(w, http.StatusConflict, "Git Repository is empty.")
+
+				return
+			}
+
+			branch := r.URL.Query().Get("sha")
+			if branch == "" {
+				branch = repo.DefaultBranch
+			}
+
+			v, ok := repo.Commits[branch]
+			if !ok {
+				writeMessage(w, http.StatusNotFound, "No commit found for SHA: "+branch)
+
+				return
+			}
+
+			writeJSON(w, []commit{{SHA: v}})
+		})
+	})
+}
+
+// parseRepositoryPath parses the path like /repositories/id[/sub],
+// and sub is empty if the path is the repo itself.
+func parseRepositoryPath(path string) (id, sub string, ok bool) {
+	v := strings.Split(path, "/")
+	if len(v) < 3 || len(v) > 4 || v[0] != "" || v[1] != "repositories" {
+		return
+	}
+
+	if len(v) == 4 {
+		sub = v[3]
+	}
+
+	return v[2], sub, true
+}
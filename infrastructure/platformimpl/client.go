@@ -0,0 +1,64 @@
+package platformimpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const maxErrorBodySize = 1024
+
+// restClient is a minimal client of the REST api of platform.
+type restClient struct {
+	cli    http.Client
+	header map[string]string
+}
+
+func newRestClient(header map[string]string) restClient {
+	return restClient{
+		cli:    http.Client{Timeout: 30 * time.Second},
+		header: header,
+	}
+}
+
+// get requests the url and decodes the response into v if it is 2xx.
+// It returns the status code, and the error if the status code is not 2xx.
+func (c *restClient) get(url string, v interface{}) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for k, item := range c.header {
+		req.Header.Set(k, item)
+	}
+
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		// the query of url may include the token, don't expose it.
+		if v, ok := err.(*neturl.Error); ok {
+			err = v.Err
+		}
+
+		return 0, fmt.Errorf("request %s failed, err:%s", req.URL.Path, err.Error())
+	}
+
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	if code < http.StatusOK || code >= http.StatusMultipleChoices {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+		return code, fmt.Errorf(
+			"request %s failed, status code:%d, body:%s",
+			req.URL.Path, code, strings.TrimSpace(string(b)),
+		)
+	}
+
+	return code, json.NewDecoder(resp.Body).Decode(v)
+}
@@ -1,8 +1,95 @@
 package platformimpl
 
+import (
+	"errors"
+	"strings"
+)
+
 type Config struct {
 	Token string `json:"token" required:"true"`
 
 	// Host is like https://gitlab.com
 	Host string `json:"host" required:"true"`
 }
+
+type GithubConfig struct {
+	Token string `json:"token" required:"true"`
+
+	// Host is like https://github.com which is the default value.
+	Host string `json:"host"`
+
+	// APIHost is like https://api.github.com which is the default value.
+	APIHost string `json:"api_host"`
+}
+
+func (cfg *GithubConfig) SetDefault() {
+	if cfg.Host == "" {
+		cfg.Host = "https://github.com"
+	}
+
+	if cfg.APIHost == "" {
+		cfg.APIHost = "https://api.github.com"
+	}
+
+	cfg.Host = strings.TrimSuffix(cfg.Host, "/")
+	cfg.APIHost = strings.TrimSuffix(cfg.APIHost, "/")
+}
+
+type GiteaConfig struct {
+	Token string `json:"token" required:"true"`
+
+	// Host is like https://gitea.com
+	Host string `json:"host" required:"true"`
+}
+
+func (cfg *GiteaConfig) SetDefault() {
+	cfg.Host = strings.TrimSuffix(cfg.Host, "/")
+}
+
+type GiteeConfig struct {
+	Token string `json:"token" required:"true"`
+
+	// Host is like https://gitee.com which is the default value.
+	Host string `json:"host"`
+
+	// APIHost is like https://gitee.com/api/v5 which is the default value.
+	APIHost string `json:"api_host"`
+}
+
+func (cfg *GiteeConfig) SetDefault() {
+	if cfg.Host == "" {
+		cfg.Host = "https://gitee.com"
+	}
+
+	cfg.Host = strings.TrimSuffix(cfg.Host, "/")
+
+	if cfg.APIHost == "" {
+		cfg.APIHost = cfg.Host + "/api/v5"
+	}
+
+	cfg.APIHost = strings.TrimSuffix(cfg.APIHost, "/")
+}
+
+type GitConfig struct {
+	// URLTemplate is the url of repo in which {owner} and {repo} will be
+	// replaced, like https://git.example.com/{owner}/{repo}.git,
+	// git://127.0.0.1/{owner}/{repo} or /srv/git/{owner}/{repo}.git
+	URLTemplate string `json:"url_template" required:"true"`
+
+	// The unit is second.
+	Timeout int `json:"timeout"`
+}
+
+func (cfg *GitConfig) SetDefault() {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60
+	}
+}
+
+func (cfg *GitConfig) Validate() error {
+	if !strings.Contains(cfg.URLTemplate, placeholderRepo) {
+		return errors.New("url_template must include " + placeholderRepo)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package platformimpl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const (
+	placeholderOwner = "{owner}"
+	placeholderRepo  = "{repo}"
+
+	// git exits with it when the remote is unavailable.
+	gitExitCodeFatal = 128
+)
+
+// the messages of git when the remote repo doesn't exist.
+var gitRepoNotExistsMsgs = []string{
+	"not found",
+	"does not exist",
+	"does not appear to be a git repository",
+	"repository not exported",
+}
+
+// NewGitPlatform returns the platform which needs no api but git.
+func NewGitPlatform(cfg *GitConfig) platform.Platform {
+	return &gitImpl{
+		template: cfg.URLTemplate,
+		timeout:  time.Duration(cfg.Timeout) * time.Second,
+	}
+}
+
+type gitImpl struct {
+	template string
+	timeout  time.Duration
+}
+
+func (h *gitImpl) GetCloneURL(owner, repo string) string {
+	return strings.NewReplacer(
+		placeholderOwner, owner,
+		placeholderRepo, repo,
+	).Replace(h.template)
+}
+
+// GetCredential returns empty credential. The git server is accessed
+// anonymously or by the credential helper of environment.
+func (h *gitImpl) GetCredential() platform.Credential {
+	return platform.Credential{}
+}
+
+func (h *gitImpl) GetLastCommit(repo *platform.Repo, branch string) (string, error) {
+	ref := "HEAD"
+	if branch != "" {
+		ref = "refs/heads/" + branch
+	}
+
+	out, err := h.lsRemote(h.GetCloneURL(repo.Owner, repo.Name), ref)
+	if err != nil {
+		return "", err
+	}
+
+	// the output is like: sha \t ref \n
+	// It is empty if the repo is empty or the branch doesn't exist.
+	for _, line := range strings.Split(string(out), "\n") {
+		if v := strings.Fields(line); len(v) == 2 && v[1] == ref {
+			return v[0], nil
+		}
+	}
+
+	return "", nil
+}
+
+func (h *gitImpl) lsRemote(url, ref string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	c := exec.CommandContext(ctx, "git", "ls-remote", "--", url, ref)
+	c.Env = append(
+		os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		// the messages of git are matched with gitRepoNotExistsMsgs,
+		// so they must not be translated.
+		"LC_ALL=C",
+	)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	err := c.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+
+	// the url may include the token, don't return the original error.
+	msg := strings.ToLower(stderr.String())
+
+	var e *exec.ExitError
+	if errors.As(err, &e) && e.ExitCode() == gitExitCodeFatal {
+		for _, item := range gitRepoNotExistsMsgs {
+			if strings.Contains(msg, item) {
+				return nil, platform.NewErrorRepoNotExists(
+					errors.New("git ls-remote failed, the repo doesn't exist"),
+				)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("git ls-remote timeout after %s", h.timeout)
+	}
+
+	return nil, fmt.Errorf("git ls-remote failed, exit code:%d", c.ProcessState.ExitCode())
+}
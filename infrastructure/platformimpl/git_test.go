@@ -0,0 +1,123 @@
+package platformimpl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func TestGitGetLastCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not found")
+	}
+
+	root := t.TempDir()
+
+	runGit := func(dir string, args ...string) string {
+		t.Helper()
+
+		c := exec.Command("git", args...)
+		c.Dir = dir
+		c.Env = append(
+			os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+
+		out, err := c.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed, err:%v, output:%s", args, err, out)
+		}
+
+		return strings.TrimSpace(string(out))
+	}
+
+	initBare := func(name string) string {
+		t.Helper()
+
+		dir := filepath.Join(root, "owner", name+".git")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		runGit(dir, "init", "-q", "--bare")
+		runGit(dir, "symbolic-ref", "HEAD", "refs/heads/main")
+
+		return dir
+	}
+
+	// owner/repo: main is the default branch, and dev is ahead of it.
+	bare := initBare("repo")
+	initBare("empty")
+
+	work := filepath.Join(root, "work")
+	runGit(root, "clone", "-q", bare, work)
+	runGit(work, "checkout", "-q", "-b", "main")
+	runGit(work, "commit", "-q", "--allow-empty", "-m", "first")
+	mainCommit := runGit(work, "rev-parse", "HEAD")
+	runGit(work, "checkout", "-q", "-b", "dev")
+	runGit(work, "commit", "-q", "--allow-empty", "-m", "second")
+	devCommit := runGit(work, "rev-parse", "HEAD")
+	runGit(work, "push", "-q", "origin", "main", "dev")
+
+	cfg := GitConfig{URLTemplate: filepath.Join(root, "{owner}", "{repo}.git")}
+	cfg.SetDefault()
+
+	h := NewGitPlatform(&cfg)
+
+	cases := []struct {
+		name         string
+		repo         string
+		branch       string
+		want         string
+		wantNotExist bool
+	}{
+		{name: "default branch", repo: "repo", want: mainCommit},
+		{name: "branch", repo: "repo", branch: "dev", want: devCommit},
+		{name: "missing branch", repo: "repo", branch: "missing"},
+		{name: "empty repo", repo: "empty"},
+		{name: "missing repo", repo: "missing", wantNotExist: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := h.GetLastCommit(
+				&platform.Repo{Owner: "owner", Name: c.repo}, c.branch,
+			)
+
+			if c.wantNotExist {
+				if !platform.IsErrorRepoNotExists(err) {
+					t.Errorf("got %v, want the error of repo not exists", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v != c.want {
+				t.Errorf("got %q, want %q", v, c.want)
+			}
+		})
+	}
+}
+
+func TestGitGetCloneURL(t *testing.T) {
+	h := NewGitPlatform(&GitConfig{
+		URLTemplate: "https://git.example.com/{owner}/{repo}.git",
+	})
+
+	if v := h.GetCloneURL("owner", "repo"); v != "https://git.example.com/owner/repo.git" {
+		t.Errorf("got %s", v)
+	}
+
+	if c := h.GetCredential(); !c.IsEmpty() {
+		t.Errorf("got credential %+v, want empty", c)
+	}
+}
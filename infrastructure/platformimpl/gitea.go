@@ -0,0 +1,96 @@
+package platformimpl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func NewGiteaPlatform(cfg *GiteaConfig) platform.Platform {
+	return &giteaImpl{
+		cli: newRestClient(map[string]string{
+			"Authorization": "token " + cfg.Token,
+		}),
+		apiHost:  cfg.Host + "/api/v1",
+		endpoint: cfg.Host,
+		credential: platform.Credential{
+			Username: "oauth2",
+			Password: cfg.Token,
+		},
+	}
+}
+
+type giteaImpl struct {
+	cli        restClient
+	apiHost    string
+	endpoint   string
+	credential platform.Credential
+}
+
+func (h *giteaImpl) GetCloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", h.endpoint, owner, repo)
+}
+
+func (h *giteaImpl) GetCredential() platform.Credential {
+	return h.credential
+}
+
+// GetLastCommit returns empty if the repo is empty or the branch doesn't exist.
+func (h *giteaImpl) GetLastCommit(repo *platform.Repo, branch string) (string, error) {
+	q := url.Values{}
+	q.Set("limit", "1")
+	q.Set("stat", "false")
+
+	if branch != "" {
+		q.Set("sha", branch)
+	}
+
+	var v []struct {
+		SHA string `json:"sha"`
+	}
+
+	code, err := h.cli.get(
+		fmt.Sprintf(
+			"%s/repos/%s/%s/commits?%s", h.apiHost,
+			url.PathEscape(repo.Owner), url.PathEscape(repo.Name), q.Encode(),
+		),
+		&v,
+	)
+
+	switch code {
+	case http.StatusNotFound:
+		return "", h.checkRepo(repo, err)
+
+	case http.StatusConflict:
+		// the repo is empty
+		return "", nil
+	}
+
+	if err != nil || len(v) == 0 {
+		return "", err
+	}
+
+	return v[0].SHA, nil
+}
+
+// checkRepo tells whether the repo or the branch is not found, since
+// both of them are 404 when listing the commits. It returns nil if
+// the repo exists, which means the branch doesn't exist.
+func (h *giteaImpl) checkRepo(repo *platform.Repo, err error) error {
+	var v struct{}
+
+	code, err1 := h.cli.get(
+		fmt.Sprintf(
+			"%s/repos/%s/%s", h.apiHost,
+			url.PathEscape(repo.Owner), url.PathEscape(repo.Name),
+		),
+		&v,
+	)
+	if code == http.StatusNotFound {
+		return platform.NewErrorRepoNotExists(err)
+	}
+
+	return err1
+}
@@ -0,0 +1,96 @@
+package platformimpl
+
+import (
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/platformfake"
+)
+
+func TestGiteaGetLastCommit(t *testing.T) {
+	srv := platformfake.NewGiteaServer("token")
+	defer srv.Close()
+
+	srv.SetRepo(platformfake.Repo{
+		Owner:         "owner",
+		Name:          "repo",
+		DefaultBranch: "main",
+		Commits:       map[string]string{"main": "c1", "dev": "c2"},
+	})
+	srv.SetRepo(platformfake.Repo{Owner: "owner", Name: "empty"})
+
+	cfg := GiteaConfig{Host: srv.URL + "/", Token: "token"}
+	cfg.SetDefault()
+
+	testGetLastCommit(t, NewGiteaPlatform(&cfg))
+
+	t.Run("invalid token", func(t *testing.T) {
+		cfg := GiteaConfig{Host: srv.URL, Token: "invalid"}
+
+		_, err := NewGiteaPlatform(&cfg).GetLastCommit(
+			&platform.Repo{Owner: "owner", Name: "repo"}, "",
+		)
+		if err == nil || platform.IsErrorRepoNotExists(err) {
+			t.Errorf("got %v, want the error of auth", err)
+		}
+	})
+}
+
+func TestGiteaGetCloneURL(t *testing.T) {
+	cfg := GiteaConfig{Host: "https://gitea.com/", Token: "token"}
+	cfg.SetDefault()
+
+	h := NewGiteaPlatform(&cfg)
+
+	if v := h.GetCloneURL("owner", "repo"); v != "https://gitea.com/owner/repo.git" {
+		t.Errorf("got %s", v)
+	}
+
+	if c := h.GetCredential(); c.Password != "token" || c.Username == "" {
+		t.Errorf("got %+v", c)
+	}
+}
+
+// testGetLastCommit checks the platform which serves the repos below,
+// and the id of each repo is its name.
+// owner/repo: main is the default branch at c1, and dev is at c2.
+// owner/empty: it has no commit.
+func testGetLastCommit(t *testing.T, h platform.Platform) {
+	cases := []struct {
+		name         string
+		repo         string
+		branch       string
+		want         string
+		wantNotExist bool
+	}{
+		{name: "default branch", repo: "repo", want: "c1"},
+		{name: "branch", repo: "repo", branch: "dev", want: "c2"},
+		{name: "missing branch", repo: "repo", branch: "missing"},
+		{name: "empty repo", repo: "empty"},
+		{name: "missing repo", repo: "missing", wantNotExist: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := h.GetLastCommit(
+				&platform.Repo{Id: c.repo, Owner: "owner", Name: c.repo}, c.branch,
+			)
+
+			if c.wantNotExist {
+				if !platform.IsErrorRepoNotExists(err) {
+					t.Errorf("got %v, want the error of repo not exists", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v != c.want {
+				t.Errorf("got %q, want %q", v, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package platformimpl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func NewGiteePlatform(cfg *GiteeConfig) (platform.Platform, error) {
+	h := &giteeImpl{
+		cli:      newRestClient(nil),
+		token:    cfg.Token,
+		apiHost:  cfg.APIHost,
+		endpoint: cfg.Host,
+	}
+
+	// the username is needed to clone by https.
+	var u struct {
+		Login string `json:"login"`
+	}
+
+	if _, err := h.cli.get(h.url("/user", url.Values{}), &u); err != nil {
+		return nil, err
+	}
+
+	h.credential = platform.Credential{
+		Username: u.Login,
+		Password: cfg.Token,
+	}
+
+	return h, nil
+}
+
+type giteeImpl struct {
+	cli        restClient
+	token      string
+	apiHost    string
+	endpoint   string
+	credential platform.Credential
+}
+
+// url returns the url of api. Gitee accepts the token by query only.
+func (h *giteeImpl) url(path string, q url.Values) string {
+	q.Set("access_token", h.token)
+
+	return h.apiHost + path + "?" + q.Encode()
+}
+
+func (h *giteeImpl) GetCloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", h.endpoint, owner, repo)
+}
+
+func (h *giteeImpl) GetCredential() platform.Credential {
+	return h.credential
+}
+
+// GetLastCommit returns empty if the repo is empty or the branch doesn't exist.
+func (h *giteeImpl) GetLastCommit(repo *platform.Repo, branch string) (string, error) {
+	q := url.Values{}
+	q.Set("per_page", "1")
+
+	if branch != "" {
+		q.Set("sha", branch)
+	}
+
+	var v []struct {
+		SHA string `json:"sha"`
+	}
+
+	code, err := h.cli.get(
+		h.url(
+			fmt.Sprintf(
+				"/repos/%s/%s/commits",
+				url.PathEscape(repo.Owner), url.PathEscape(repo.Name),
+			),
+			q,
+		),
+		&v,
+	)
+	if code == http.StatusNotFound {
+		return "", h.checkRepo(repo, err)
+	}
+
+	if err != nil || len(v) == 0 {
+		return "", err
+	}
+
+	return v[0].SHA, nil
+}
+
+// checkRepo tells whether the repo or the branch is not found, since
+// both of them are 404 when listing the commits. It returns nil if
+// the repo exists, which means the branch doesn't exist.
+func (h *giteeImpl) checkRepo(repo *platform.Repo, err error) error {
+	var v struct{}
+
+	code, err1 := h.cli.get(
+		h.url(
+			fmt.Sprintf(
+				"/repos/%s/%s",
+				url.PathEscape(repo.Owner), url.PathEscape(repo.Name),
+			),
+			url.Values{},
+		),
+		&v,
+	)
+	if code == http.StatusNotFound {
+		return platform.NewErrorRepoNotExists(err)
+	}
+
+	return err1
+}
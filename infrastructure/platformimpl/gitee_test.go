@@ -0,0 +1,50 @@
+package platformimpl
+
+import (
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/platformfake"
+)
+
+func TestGiteeGetLastCommit(t *testing.T) {
+	srv := platformfake.NewGiteeServer("token", "robot")
+	defer srv.Close()
+
+	srv.SetRepo(platformfake.Repo{
+		Owner:         "owner",
+		Name:          "repo",
+		DefaultBranch: "main",
+		Commits:       map[string]string{"main": "c1", "dev": "c2"},
+	})
+	srv.SetRepo(platformfake.Repo{Owner: "owner", Name: "empty"})
+
+	cfg := GiteeConfig{Host: srv.URL, Token: "token"}
+	cfg.SetDefault()
+
+	h, err := NewGiteePlatform(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := h.GetCredential(); c.Username != "robot" || c.Password != "token" {
+		t.Errorf("got credential %+v", c)
+	}
+
+	if v := h.GetCloneURL("owner", "repo"); v != srv.URL+"/owner/repo.git" {
+		t.Errorf("got clone url %s", v)
+	}
+
+	testGetLastCommit(t, h)
+}
+
+func TestGiteeInvalidToken(t *testing.T) {
+	srv := platformfake.NewGiteeServer("token", "robot")
+	defer srv.Close()
+
+	cfg := GiteeConfig{Host: srv.URL, Token: "invalid"}
+	cfg.SetDefault()
+
+	if _, err := NewGiteePlatform(&cfg); err == nil {
+		t.Error("want the error of auth")
+	}
+}
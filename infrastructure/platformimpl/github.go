@@ -0,0 +1,95 @@
+package platformimpl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func NewGithubPlatform(cfg *GithubConfig) platform.Platform {
+	return &githubImpl{
+		cli: newRestClient(map[string]string{
+			"Accept":        "application/vnd.github+json",
+			"Authorization": "Bearer " + cfg.Token,
+		}),
+		apiHost:  cfg.APIHost,
+		endpoint: cfg.Host,
+		credential: platform.Credential{
+			Username: "x-access-token",
+			Password: cfg.Token,
+		},
+	}
+}
+
+type githubImpl struct {
+	cli        restClient
+	apiHost    string
+	endpoint   string
+	credential platform.Credential
+}
+
+func (h *githubImpl) GetCloneURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s.git", h.endpoint, owner, repo)
+}
+
+func (h *githubImpl) GetCredential() platform.Credential {
+	return h.credential
+}
+
+// GetLastCommit looks up the repo by its id, so that it still works
+// after the repo is renamed or transferred. It returns empty if the
+// repo is empty or the branch doesn't exist.
+func (h *githubImpl) GetLastCommit(repo *platform.Repo, branch string) (string, error) {
+	q := url.Values{}
+	q.Set("per_page", "1")
+
+	if branch != "" {
+		q.Set("sha", branch)
+	}
+
+	var v []struct {
+		SHA string `json:"sha"`
+	}
+
+	code, err := h.cli.get(
+		fmt.Sprintf(
+			"%s/repositories/%s/commits?%s",
+			h.apiHost, url.PathEscape(repo.Id), q.Encode(),
+		),
+		&v,
+	)
+
+	switch code {
+	case http.StatusNotFound:
+		return "", h.checkRepo(repo, err)
+
+	case http.StatusConflict:
+		// the repo is empty
+		return "", nil
+	}
+
+	if err != nil || len(v) == 0 {
+		return "", err
+	}
+
+	return v[0].SHA, nil
+}
+
+// checkRepo tells whether the repo or the branch is not found, since
+// both of them are 404 when listing the commits. It returns nil if
+// the repo exists, which means the branch doesn't exist.
+func (h *githubImpl) checkRepo(repo *platform.Repo, err error) error {
+	var v struct{}
+
+	code, err1 := h.cli.get(
+		fmt.Sprintf("%s/repositories/%s", h.apiHost, url.PathEscape(repo.Id)),
+		&v,
+	)
+	if code == http.StatusNotFound {
+		return platform.NewErrorRepoNotExists(err)
+	}
+
+	return err1
+}
@@ -0,0 +1,53 @@
+package platformimpl
+
+import (
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/platformfake"
+)
+
+func TestGithubGetLastCommit(t *testing.T) {
+	srv := platformfake.NewGithubServer("token")
+	defer srv.Close()
+
+	srv.SetRepo(platformfake.Repo{
+		Id:            "repo",
+		Owner:         "owner",
+		Name:          "repo",
+		DefaultBranch: "main",
+		Commits:       map[string]string{"main": "c1", "dev": "c2"},
+	})
+	srv.SetRepo(platformfake.Repo{Id: "empty", Owner: "owner", Name: "empty"})
+
+	cfg := GithubConfig{APIHost: srv.URL + "/", Token: "token"}
+	cfg.SetDefault()
+
+	testGetLastCommit(t, NewGithubPlatform(&cfg))
+
+	t.Run("invalid token", func(t *testing.T) {
+		cfg := GithubConfig{APIHost: srv.URL, Token: "invalid"}
+
+		_, err := NewGithubPlatform(&cfg).GetLastCommit(
+			&platform.Repo{Id: "repo", Owner: "owner", Name: "repo"}, "",
+		)
+		if err == nil || platform.IsErrorRepoNotExists(err) {
+			t.Errorf("got %v, want the error of auth", err)
+		}
+	})
+}
+
+func TestGithubGetCloneURL(t *testing.T) {
+	cfg := GithubConfig{Token: "token"}
+	cfg.SetDefault()
+
+	h := NewGithubPlatform(&cfg)
+
+	if v := h.GetCloneURL("owner", "repo"); v != "https://github.com/owner/repo.git" {
+		t.Errorf("got %s", v)
+	}
+
+	if c := h.GetCredential(); c.Username != "x-access-token" || c.Password != "token" {
+		t.Errorf("got %+v", c)
+	}
+}
@@ -2,6 +2,7 @@ package platformimpl
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	gitlab "github.com/xanzy/go-gitlab"
@@ -24,31 +25,41 @@ func NewPlatform(cfg *Config) (platform.Platform, error) {
 	}
 
 	return &platformImpl{
-		cli: cli,
-		endpoint: strings.Replace(
-			strings.TrimSuffix(cfg.Host, "/"), "://",
-			fmt.Sprintf("://%s:%s@", u.Username, cfg.Token), 1,
-		),
+		cli:      cli,
+		endpoint: strings.TrimSuffix(cfg.Host, "/"),
+		credential: platform.Credential{
+			Username: u.Username,
+			Password: cfg.Token,
+		},
 	}, nil
 }
 
 type platformImpl struct {
-	cli      *gitlab.Client
-	endpoint string
+	cli        *gitlab.Client
+	endpoint   string
+	credential platform.Credential
 }
 
 func (h *platformImpl) GetCloneURL(owner, repo string) string {
 	return fmt.Sprintf("%s/%s/%s", h.endpoint, owner, repo)
 }
 
-func (h *platformImpl) GetLastCommit(pid string) (string, error) {
+func (h *platformImpl) GetCredential() platform.Credential {
+	return h.credential
+}
+
+func (h *platformImpl) GetLastCommit(repo *platform.Repo, branch string) (string, error) {
 	opts := gitlab.ListCommitsOptions{}
 	opts.Page = 1
 	opts.PerPage = 1
 
-	v, resp, err := h.cli.Commits.ListCommits(pid, &opts, nil)
+	if branch != "" {
+		opts.RefName = gitlab.String(branch)
+	}
+
+	v, resp, err := h.cli.Commits.ListCommits(repo.Id, &opts, nil)
 	if err != nil {
-		if resp.StatusCode == 404 {
+		if resp != nil && resp.StatusCode == 404 {
 			err = platform.NewErrorRepoNotExists(err)
 		}
 
@@ -61,3 +72,52 @@ func (h *platformImpl) GetLastCommit(pid string) (string, error) {
 
 	return v[0].ID, nil
 }
+
+// GetRepoSize requires the reporter role of repo at least,
+// otherwise the statistics are not returned.
+func (h *platformImpl) GetRepoSize(repo *platform.Repo) (int64, error) {
+	v, resp, err := h.cli.Projects.GetProject(
+		repo.Id, &gitlab.GetProjectOptions{Statistics: gitlab.Bool(true)},
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			err = platform.NewErrorRepoNotExists(err)
+		}
+
+		return 0, err
+	}
+
+	if v.Statistics == nil {
+		return 0, nil
+	}
+
+	return v.Statistics.RepositorySize, nil
+}
+
+func (h *platformImpl) ListRepos(page int) ([]platform.Repo, bool, error) {
+	opts := gitlab.ListProjectsOptions{}
+	opts.Page = page
+	opts.PerPage = 100
+	opts.Simple = gitlab.Bool(true)
+
+	v, resp, err := h.cli.Projects.ListProjects(&opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r := make([]platform.Repo, 0, len(v))
+	for _, item := range v {
+		owner, name := "", item.Path
+		if item.Namespace != nil {
+			owner = item.Namespace.FullPath
+		}
+
+		r = append(r, platform.Repo{
+			Id:    strconv.Itoa(item.ID),
+			Owner: owner,
+			Name:  name,
+		})
+	}
+
+	return r, resp.NextPage != 0, nil
+}
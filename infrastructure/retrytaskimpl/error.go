@@ -0,0 +1,34 @@
+package retrytaskimpl
+
+import "github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+
+type errorDataNotExists struct {
+	error
+}
+
+func NewErrorDataNotExists(err error) errorDataNotExists {
+	return errorDataNotExists{err}
+}
+
+type errorConcurrentUpdating struct {
+	error
+}
+
+func NewErrorConcurrentUpdating(err error) errorConcurrentUpdating {
+	return errorConcurrentUpdating{err}
+}
+
+func convertError(err error) (out error) {
+	switch err.(type) {
+	case errorDataNotExists:
+		out = retrytask.NewErrorTaskNotExists(err)
+
+	case errorConcurrentUpdating:
+		out = retrytask.NewErrorConcurrentUpdating(err)
+
+	default:
+		out = err
+	}
+
+	return
+}
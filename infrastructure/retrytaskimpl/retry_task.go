@@ -0,0 +1,149 @@
+package retrytaskimpl
+
+import (
+	"encoding/json"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+)
+
+type RetryTaskMapper interface {
+	Insert(*RetryTaskDO) (string, error)
+	Update(*RetryTaskDO) error
+	Get(string) (RetryTaskDO, error)
+	ListDue(now int64, limit int) ([]RetryTaskDO, error)
+	ListQuarantined() ([]RetryTaskDO, error)
+	Delete(string) error
+}
+
+func NewRetryTaskRepo(mapper RetryTaskMapper) retrytask.RetryTaskRepo {
+	return retryTask{mapper}
+}
+
+type retryTask struct {
+	mapper RetryTaskMapper
+}
+
+func (impl retryTask) Save(t *domain.RetryTask) (r domain.RetryTask, err error) {
+	do, err := impl.toRetryTaskDO(t)
+	if err != nil {
+		return
+	}
+
+	if t.Id != "" {
+		if err = impl.mapper.Update(&do); err != nil {
+			err = convertError(err)
+		} else {
+			r = *t
+			r.Version += 1
+		}
+
+		return
+	}
+
+	v, err := impl.mapper.Insert(&do)
+	if err != nil {
+		err = convertError(err)
+	} else {
+		r = *t
+		r.Id = v
+	}
+
+	return
+}
+
+func (impl retryTask) Find(id string) (r domain.RetryTask, err error) {
+	v, err := impl.mapper.Get(id)
+	if err != nil {
+		err = convertError(err)
+	} else {
+		err = v.toRetryTask(&r)
+	}
+
+	return
+}
+
+func (impl retryTask) FindDue(now int64, limit int) ([]domain.RetryTask, error) {
+	v, err := impl.mapper.ListDue(now, limit)
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	return impl.toRetryTasks(v)
+}
+
+func (impl retryTask) FindQuarantined() ([]domain.RetryTask, error) {
+	v, err := impl.mapper.ListQuarantined()
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	return impl.toRetryTasks(v)
+}
+
+func (impl retryTask) Delete(id string) error {
+	return convertError(impl.mapper.Delete(id))
+}
+
+func (impl retryTask) toRetryTasks(v []RetryTaskDO) ([]domain.RetryTask, error) {
+	r := make([]domain.RetryTask, len(v))
+
+	for i := range v {
+		if err := v[i].toRetryTask(&r[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (impl retryTask) toRetryTaskDO(t *domain.RetryTask) (RetryTaskDO, error) {
+	header, err := json.Marshal(t.Header)
+	if err != nil {
+		return RetryTaskDO{}, err
+	}
+
+	return RetryTaskDO{
+		Id:          t.Id,
+		EventId:     t.EventId,
+		Topic:       t.Topic,
+		Header:      string(header),
+		Payload:     string(t.Payload),
+		Attempts:    t.Attempts,
+		LastError:   t.LastError,
+		NextRetryAt: t.NextRetryAt,
+		Quarantined: t.Quarantined,
+		Version:     t.Version,
+	}, nil
+}
+
+type RetryTaskDO struct {
+	Id          string
+	EventId     string
+	Topic       string
+	Header      string
+	Payload     string
+	Attempts    int
+	LastError   string
+	NextRetryAt int64
+	Quarantined bool
+	Version     int
+}
+
+func (do *RetryTaskDO) toRetryTask(r *domain.RetryTask) error {
+	r.Id = do.Id
+	r.EventId = do.EventId
+	r.Topic = do.Topic
+	r.Payload = []byte(do.Payload)
+	r.Attempts = do.Attempts
+	r.LastError = do.LastError
+	r.NextRetryAt = do.NextRetryAt
+	r.Quarantined = do.Quarantined
+	r.Version = do.Version
+
+	if do.Header == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(do.Header), &r.Header)
+}
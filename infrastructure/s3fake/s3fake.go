@@ -0,0 +1,328 @@
+// Package s3fake provides a fake s3 server which keeps the objects of
+// one bucket in memory, so that the s3 implementation can be tested
+// offline. It supports the single part requests only.
+package s3fake
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	streamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+	timeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+type object struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+// Server is the fake s3 server. Its url is the endpoint of s3.
+type Server struct {
+	*httptest.Server
+
+	bucket string
+
+	lock    sync.RWMutex
+	objects map[string]object
+}
+
+func NewServer(bucket string) *Server {
+	s := &Server{
+		bucket:  bucket,
+		objects: map[string]object{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Endpoint returns the endpoint without scheme.
+func (s *Server) Endpoint() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		writeError(w, http.StatusForbidden, "AccessDenied", "")
+
+		return
+	}
+
+	v := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if v[0] != s.bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "")
+
+		return
+	}
+
+	key := ""
+	if len(v) == 2 {
+		key = v[1]
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.listObjects(w, r)
+
+	case key == "":
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "")
+
+	case r.Method == http.MethodPut && r.Header.Get("x-amz-copy-source") != "":
+		s.copyObject(w, r, key)
+
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, key)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		s.getObject(w, r, key)
+
+	case r.Method == http.MethodDelete:
+		s.lock.Lock()
+		delete(s.objects, key)
+		s.lock.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", key)
+	}
+}
+
+func (s *Server) save(key string, data []byte) object {
+	sum := md5.Sum(data)
+
+	o := object{
+		data:     data,
+		etag:     hex.EncodeToString(sum[:]),
+		modified: time.Now().UTC(),
+	}
+
+	s.lock.Lock()
+	s.objects[key] = o
+	s.lock.Unlock()
+
+	return o
+}
+
+func (s *Server) get(key string) (object, bool) {
+	s.lock.RLock()
+	o, ok := s.objects[key]
+	s.lock.RUnlock()
+
+	return o, ok
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, key string) {
+	if r.URL.Query().Get("uploadId") != "" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented", key)
+
+		return
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if r.Header.Get("x-amz-content-sha256") == streamingPayload {
+		data, err = readChunked(r.Body)
+	} else {
+		data, err = ioutil.ReadAll(r.Body)
+	}
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", key)
+
+		return
+	}
+
+	o := s.save(key, data)
+
+	w.Header().Set("ETag", `"`+o.etag+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, key string) {
+	src, err := url.PathUnescape(r.Header.Get("x-amz-copy-source"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", key)
+
+		return
+	}
+
+	v := strings.SplitN(strings.TrimPrefix(src, "/"), "/", 2)
+	if len(v) != 2 || v[0] != s.bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", key)
+
+		return
+	}
+
+	from, ok := s.get(v[1])
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", v[1])
+
+		return
+	}
+
+	o := s.save(key, from.data)
+
+	writeXML(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string   `xml:"ETag"`
+		LastModified string   `xml:"LastModified"`
+	}{
+		ETag:         `"` + o.etag + `"`,
+		LastModified: o.modified.Format(timeFormat),
+	})
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	o, ok := s.get(key)
+	if !ok {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			writeError(w, http.StatusNotFound, "NoSuchKey", key)
+		}
+
+		return
+	}
+
+	h := w.Header()
+	h.Set("ETag", `"`+o.etag+`"`)
+	h.Set("Last-Modified", o.modified.Format(http.TimeFormat))
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set("Content-Length", strconv.Itoa(len(o.data)))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(o.data)
+	}
+}
+
+type listEntry struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	ETag         string `xml:"ETag"`
+	LastModified string `xml:"LastModified"`
+}
+
+// listObjects lists all the objects under the prefix in one page.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	s.lock.RLock()
+	entries := make([]listEntry, 0, len(s.objects))
+	for k, o := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			entries = append(entries, listEntry{
+				Key:          k,
+				Size:         int64(len(o.data)),
+				ETag:         `"` + o.etag + `"`,
+				LastModified: o.modified.Format(timeFormat),
+			})
+		}
+	}
+	s.lock.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	writeXML(w, struct {
+		XMLName     xml.Name    `xml:"ListBucketResult"`
+		Name        string      `xml:"Name"`
+		Prefix      string      `xml:"Prefix"`
+		KeyCount    int         `xml:"KeyCount"`
+		MaxKeys     int         `xml:"MaxKeys"`
+		IsTruncated bool        `xml:"IsTruncated"`
+		Contents    []listEntry `xml:"Contents"`
+	}{
+		Name:     s.bucket,
+		Prefix:   prefix,
+		KeyCount: len(entries),
+		MaxKeys:  len(entries),
+		Contents: entries,
+	})
+}
+
+// readChunked decodes the body of aws-chunked encoding which is like
+// hex(size);chunk-signature=xxx\r\n data \r\n ... 0;chunk-signature=xxx\r\n\r\n
+// The signatures are not verified.
+func readChunked(body io.Reader) ([]byte, error) {
+	br := bufio.NewReader(body)
+
+	var r []byte
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		v := strings.SplitN(strings.TrimSpace(line), ";", 2)
+
+		n, err := strconv.ParseInt(v[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+
+		if string(chunk[n:]) != "\r\n" {
+			return nil, errors.New("invalid chunk")
+		}
+
+		if n == 0 {
+			return r, nil
+		}
+
+		r = append(r, chunk[:n]...)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, errCode, key string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+		Key     string   `xml:"Key"`
+	}{
+		Code:    errCode,
+		Message: fmt.Sprintf("%s: %s", errCode, key),
+		Key:     key,
+	})
+}
@@ -0,0 +1,36 @@
+package s3impl
+
+import "errors"
+
+type Config struct {
+	// Endpoint is like play.min.io:9000 without scheme.
+	Endpoint  string `json:"endpoint"    required:"true"`
+	AccessKey string `json:"access_key"  required:"true"`
+	SecretKey string `json:"secret_key"  required:"true"`
+	Bucket    string `json:"bucket"      required:"true"`
+	Region    string `json:"region"`
+	UseSSL    bool   `json:"use_ssl"`
+
+	// PathStyle should be set for MinIO and Ceph which don't support
+	// the virtual-hosted style.
+	PathStyle bool `json:"path_style"`
+
+	// The unit is Mbyte. The file larger than it will be uploaded by
+	// multipart upload.
+	PartSize int `json:"part_size"`
+}
+
+func (c *Config) SetDefault() {
+	if c.PartSize <= 0 {
+		c.PartSize = 64
+	}
+}
+
+func (c *Config) Validate() error {
+	// the minimum part size of s3 is 5MB.
+	if c.PartSize < 5 {
+		return errors.New("part_size must be at least 5")
+	}
+
+	return nil
+}
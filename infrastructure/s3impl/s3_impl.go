@@ -0,0 +1,138 @@
+package s3impl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/obs"
+)
+
+// maxCopySize is the max size of object copied in one request.
+const maxCopySize = 5 << 30
+
+func NewOBS(cfg *Config) (obs.OBS, error) {
+	opts := &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	}
+
+	if cfg.PathStyle {
+		opts.BucketLookup = minio.BucketLookupPath
+	}
+
+	cli, err := minio.New(cfg.Endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Impl{
+		cli:      cli,
+		bucket:   cfg.Bucket,
+		partSize: uint64(cfg.PartSize) << 20,
+	}, nil
+}
+
+type s3Impl struct {
+	cli      *minio.Client
+	bucket   string
+	partSize uint64
+}
+
+func (s *s3Impl) SaveObject(path, content string) error {
+	_, err := s.cli.PutObject(
+		context.Background(), s.bucket, path,
+		strings.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{},
+	)
+
+	return err
+}
+
+// SaveFile uploads the file by multipart upload if it is larger
+// than the part size.
+func (s *s3Impl) SaveFile(path, localFile string) error {
+	_, err := s.cli.FPutObject(
+		context.Background(), s.bucket, path, localFile,
+		minio.PutObjectOptions{PartSize: s.partSize},
+	)
+
+	return err
+}
+
+func (s *s3Impl) GetObject(path string) ([]byte, error) {
+	output, err := s.cli.GetObject(
+		context.Background(), s.bucket, path, minio.GetObjectOptions{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer output.Close()
+
+	v, err := ioutil.ReadAll(output)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// CopyObject copies the object on the server side. The object larger
+// than 5GB will be copied by multipart copy. ComposeObject is not used
+// for the smaller one, because it always copies by multipart copy
+// unless the range of source is set.
+func (s *s3Impl) CopyObject(dst, src string) error {
+	ctx := context.Background()
+
+	info, err := s.cli.StatObject(ctx, s.bucket, src, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	dstOpts := minio.CopyDestOptions{Bucket: s.bucket, Object: dst}
+	srcOpts := minio.CopySrcOptions{Bucket: s.bucket, Object: src}
+
+	if info.Size <= maxCopySize {
+		_, err = s.cli.CopyObject(ctx, dstOpts, srcOpts)
+	} else {
+		_, err = s.cli.ComposeObject(ctx, dstOpts, srcOpts)
+	}
+
+	return err
+}
+
+func (s *s3Impl) DeleteObject(path string) error {
+	return s.cli.RemoveObject(
+		context.Background(), s.bucket, path, minio.RemoveObjectOptions{},
+	)
+}
+
+func (s *s3Impl) ListObjects(prefix string) ([]obs.ObjectInfo, error) {
+	ch := s.cli.ListObjects(
+		context.Background(), s.bucket,
+		minio.ListObjectsOptions{Prefix: prefix, Recursive: true},
+	)
+
+	var r []obs.ObjectInfo
+	for item := range ch {
+		if item.Err != nil {
+			return nil, item.Err
+		}
+
+		r = append(r, obs.ObjectInfo{
+			Path: item.Key,
+			Size: item.Size,
+		})
+	}
+
+	return r, nil
+}
@@ -0,0 +1,31 @@
+package s3impl
+
+import (
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/obs/obstest"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/s3fake"
+)
+
+func TestS3(t *testing.T) {
+	srv := s3fake.NewServer("bucket")
+	defer srv.Close()
+
+	cfg := Config{
+		Endpoint:  srv.Endpoint(),
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "bucket",
+		// the bucket location is not looked up if the region is set.
+		Region:    "us-east-1",
+		PathStyle: true,
+	}
+	cfg.SetDefault()
+
+	s, err := NewOBS(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obstest.TestOBS(t, s)
+}
@@ -0,0 +1,396 @@
+package syncengineimpl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+const (
+	diffAdded   = "A"
+	diffDeleted = "D"
+	// diffTypeChanged means the type of file is changed, such as
+	// from a regular file to a symlink or submodule.
+	diffTypeChanged = "T"
+
+	envGitUsername = "XIHE_GIT_USERNAME"
+	envGitPassword = "XIHE_GIT_PASSWORD"
+)
+
+type fileChange struct {
+	status string
+	path   string
+}
+
+type treeEntry struct {
+	oid  string
+	path string
+	size int64
+}
+
+func gitEnv() []string {
+	return append(
+		os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		// the lfs files will be copied from the lfs store directly,
+		// so it is not necessary to download them.
+		"GIT_LFS_SKIP_SMUDGE=1",
+	)
+}
+
+// runGit runs git in dir and returns the stdout only, so that the output
+// will not be polluted by the warnings git writes to stderr.
+func runGit(dir string, args ...string) ([]byte, error) {
+	return runGitWithInput(dir, nil, args...)
+}
+
+func runGitWithInput(dir string, input []byte, args ...string) ([]byte, error) {
+	return execGit(dir, input, nil, args...)
+}
+
+// runGitWithCredential runs git which gets the credential from the
+// environment variables by the credential helper, so that it will not
+// be exposed by the command line.
+func runGitWithCredential(dir string, cred *platform.Credential, args ...string) (
+	[]byte, error,
+) {
+	if cred.IsEmpty() {
+		return runGit(dir, args...)
+	}
+
+	helper := fmt.Sprintf(
+		`!f() { test "$1" = get && echo "username=$%s" && echo "password=$%s"; }; f`,
+		envGitUsername, envGitPassword,
+	)
+
+	return execGit(
+		dir, nil,
+		[]string{
+			envGitUsername + "=" + cred.Username,
+			envGitPassword + "=" + cred.Password,
+		},
+		// reset the other helpers first
+		append([]string{
+			"-c", "credential.helper=", "-c", "credential.helper=" + helper,
+		}, args...)...,
+	)
+}
+
+func execGit(dir string, input []byte, env []string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	c := exec.Command("git", args...)
+	c.Env = append(gitEnv(), env...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if input != nil {
+		c.Stdin = bytes.NewReader(input)
+	}
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf(
+			"%w, stderr:%s", err, strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func gitCloneMirror(url, dir string, cred *platform.Credential) error {
+	_, err := runGitWithCredential("", cred, "clone", "-q", "--mirror", url, dir)
+	if err != nil {
+		return fmt.Errorf("git clone mirror failed, err:%s", utils.Redact(err.Error()))
+	}
+
+	return nil
+}
+
+func gitFetchMirror(dir, url string, cred *platform.Credential) error {
+	// the url may change, for example the host is updated.
+	if _, err := runGit(dir, "remote", "set-url", "origin", url); err != nil {
+		return fmt.Errorf("git set url of mirror failed, err:%s", utils.Redact(err.Error()))
+	}
+
+	_, err := runGitWithCredential(dir, cred, "fetch", "-q", "--prune", "origin")
+	if err != nil {
+		return fmt.Errorf("git fetch mirror failed, err:%s", utils.Redact(err.Error()))
+	}
+
+	return nil
+}
+
+// gitCheckMirror returns an error if the mirror is not a bare repo
+// or its objects are broken. The contents of blobs are not checked,
+// so that it is fast enough for the large repo.
+func gitCheckMirror(dir string) error {
+	v, err := runGit(dir, "rev-parse", "--is-bare-repository")
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(v)) != "true" {
+		return errors.New("not a bare repo")
+	}
+
+	_, err = runGit(dir, "fsck", "--connectivity-only", "--no-dangling", "--no-progress")
+
+	return err
+}
+
+// gitCloneLocal checks out the ref of mirror to dir. The objects are
+// shared with the mirror instead of being copied. The ref can be
+// a branch or a tag, and it is the default branch if empty.
+func gitCloneLocal(mirror, ref, dir string) error {
+	args := []string{"clone", "-q", "--shared"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+
+	_, err := runGit("", append(args, mirror, dir)...)
+
+	return err
+}
+
+func gitLastCommit(dir string) (string, error) {
+	v, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(v)), nil
+}
+
+// gitCurrentBranch returns the branch checked out,
+// or empty if the HEAD is detached.
+func gitCurrentBranch(dir string) (string, error) {
+	v, err := runGit(dir, "symbolic-ref", "-q", "--short", "HEAD")
+	if err != nil {
+		if isExitCode(err, 1) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(v)), nil
+}
+
+// gitIsAncestor checks whether the commit exists and is the ancestor of
+// the other commit. It returns false when history was rewritten,
+// for example by force-push.
+func gitIsAncestor(dir, commit, of string) (bool, error) {
+	if _, err := runGit(dir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+		if isExitCode(err, 1) || isExitCode(err, 128) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if _, err := runGit(dir, "merge-base", "--is-ancestor", commit, of); err != nil {
+		if isExitCode(err, 1) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func isExitCode(err error, code int) bool {
+	var e *exec.ExitError
+
+	return errors.As(err, &e) && e.ExitCode() == code
+}
+
+// gitListFiles lists all the files of the tree of commit.
+func gitListFiles(dir, commit string) ([]string, error) {
+	v, err := runGit(dir, "ls-tree", "-r", "-z", "--name-only", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitNUL(v), nil
+}
+
+// gitListTree lists the regular files of the tree of commit with
+// their blob ids and sizes.
+func gitListTree(dir, commit string) ([]treeEntry, error) {
+	v, err := runGit(dir, "ls-tree", "-r", "-z", "-l", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseListTree(v)
+}
+
+func parseListTree(v []byte) ([]treeEntry, error) {
+	items := splitNUL(v)
+	r := make([]treeEntry, 0, len(items))
+
+	for _, item := range items {
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		i := strings.IndexByte(item, '\t')
+		if i < 0 {
+			return nil, errors.New("unexpected output of git ls-tree")
+		}
+
+		fields := strings.Fields(item[:i])
+		if len(fields) != 4 {
+			return nil, errors.New("unexpected output of git ls-tree")
+		}
+
+		// ignore the symlink and submodule
+		if fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		r = append(r, treeEntry{
+			oid:  fields[2],
+			path: item[i+1:],
+			size: size,
+		})
+	}
+
+	return r, nil
+}
+
+// gitSaveBlob writes the content of blob to file.
+func gitSaveBlob(dir, oid, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+
+	c := exec.Command("git", "-C", dir, "cat-file", "blob", oid)
+	c.Env = gitEnv()
+	c.Stdout = f
+	c.Stderr = &stderr
+
+	err = c.Run()
+
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	if err != nil {
+		return fmt.Errorf(
+			"%w, stderr:%s", err, strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return nil
+}
+
+// gitReadBlobs reads the contents of blobs in one batch.
+func gitReadBlobs(dir string, oids []string) (map[string][]byte, error) {
+	if len(oids) == 0 {
+		return nil, nil
+	}
+
+	v, err := runGitWithInput(
+		dir, []byte(strings.Join(oids, "\n")+"\n"), "cat-file", "--batch",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBlobs(v)
+}
+
+func parseBlobs(v []byte) (map[string][]byte, error) {
+	r := map[string][]byte{}
+
+	// <oid> SP <type> SP <size> LF <contents> LF
+	for len(v) > 0 {
+		i := bytes.IndexByte(v, '\n')
+		if i < 0 {
+			return nil, errors.New("unexpected output of git cat-file")
+		}
+
+		fields := strings.Fields(string(v[:i]))
+		if len(fields) != 3 {
+			return nil, errors.New("unexpected output of git cat-file")
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, err
+		}
+
+		v = v[i+1:]
+		if len(v) < size+1 {
+			return nil, errors.New("unexpected output of git cat-file")
+		}
+
+		r[fields[0]] = v[:size]
+		v = v[size+1:]
+	}
+
+	return r, nil
+}
+
+// gitDiff lists the changed files between the two commits.
+func gitDiff(dir, start, end string) ([]fileChange, error) {
+	v, err := runGit(
+		dir, "diff", "--name-status", "-z", "--no-renames", start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDiff(v)
+}
+
+func parseDiff(v []byte) ([]fileChange, error) {
+	items := splitNUL(v)
+	if len(items)%2 != 0 {
+		return nil, errors.New("unexpected output of git diff")
+	}
+
+	r := make([]fileChange, 0, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		r = append(r, fileChange{
+			status: items[i],
+			path:   items[i+1],
+		})
+	}
+
+	return r, nil
+}
+
+// splitNUL splits the output of git which is separated by NUL.
+// It is used to handle the file names which include spaces,
+// newlines or non-ascii characters correctly.
+func splitNUL(v []byte) []string {
+	items := strings.Split(string(v), "\x00")
+
+	r := make([]string, 0, len(items))
+	for _, item := range items {
+		if item != "" {
+			r = append(r, item)
+		}
+	}
+
+	return r
+}
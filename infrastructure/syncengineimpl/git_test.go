@@ -0,0 +1,294 @@
+package syncengineimpl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitNUL(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"trailing nul", "a\x00b\x00", []string{"a", "b"}},
+		{"no trailing nul", "a\x00b", []string{"a", "b"}},
+		{"special chars", "a b\x00c\nd\x00文件\x00", []string{"a b", "c\nd", "文件"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if v := splitNUL([]byte(c.in)); !reflect.DeepEqual(v, c.want) {
+				t.Errorf("got %q, want %q", v, c.want)
+			}
+		})
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []fileChange
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: []fileChange{},
+		},
+		{
+			name: "changes",
+			in:   "A\x00a b.txt\x00M\x00dir/c\nd\x00D\x00文件\x00",
+			want: []fileChange{
+				{status: "A", path: "a b.txt"},
+				{status: "M", path: "dir/c\nd"},
+				{status: "D", path: "文件"},
+			},
+		},
+		{
+			name:    "missing path",
+			in:      "A\x00a\x00M\x00",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := parseDiff([]byte(c.in))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, c.wantErr)
+			}
+
+			if !c.wantErr && !reflect.DeepEqual(v, c.want) {
+				t.Errorf("got %+v, want %+v", v, c.want)
+			}
+		})
+	}
+}
+
+func TestParseListTree(t *testing.T) {
+	const (
+		oid1 = "8baef1b4abc478178b004d62031cf7fe6db6f903"
+		oid2 = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+	)
+
+	cases := []struct {
+		name    string
+		in      string
+		want    []treeEntry
+		wantErr bool
+	}{
+		{
+			name: "files",
+			in: "100644 blob " + oid1 + "      12\ta b.txt\x00" +
+				"100755 blob " + oid2 + "       0\tdir/文件\x00",
+			want: []treeEntry{
+				{oid: oid1, path: "a b.txt", size: 12},
+				{oid: oid2, path: "dir/文件", size: 0},
+			},
+		},
+		{
+			name: "symlink and submodule are ignored",
+			in: "120000 blob " + oid1 + "       5\tlink\x00" +
+				"160000 commit " + oid2 + "       -\tsub\x00" +
+				"100644 blob " + oid1 + "      12\tfile\x00",
+			want: []treeEntry{
+				{oid: oid1, path: "file", size: 12},
+			},
+		},
+		{
+			name:    "no tab",
+			in:      "100644 blob " + oid1 + " 12 file\x00",
+			wantErr: true,
+		},
+		{
+			name:    "missing size",
+			in:      "100644 blob " + oid1 + "\tfile\x00",
+			wantErr: true,
+		},
+		{
+			name:    "invalid size",
+			in:      "100644 blob " + oid1 + " x\tfile\x00",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := parseListTree([]byte(c.in))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, c.wantErr)
+			}
+
+			if !c.wantErr && !reflect.DeepEqual(v, c.want) {
+				t.Errorf("got %+v, want %+v", v, c.want)
+			}
+		})
+	}
+}
+
+func TestParseBlobs(t *testing.T) {
+	const (
+		oid1 = "8baef1b4abc478178b004d62031cf7fe6db6f903"
+		oid2 = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+	)
+
+	cases := []struct {
+		name    string
+		in      string
+		want    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name: "blobs",
+			in: oid1 + " blob 9\nline\nline\n" +
+				oid2 + " blob 0\n\n",
+			want: map[string][]byte{
+				oid1: []byte("line\nline"),
+				oid2: {},
+			},
+		},
+		{
+			name:    "missing object",
+			in:      oid1 + " missing\n",
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			in:      oid1 + " blob 9\nline\n",
+			wantErr: true,
+		},
+		{
+			name:    "no header end",
+			in:      oid1 + " blob 9",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := parseBlobs([]byte(c.in))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, c.wantErr)
+			}
+
+			if !c.wantErr && !reflect.DeepEqual(v, c.want) {
+				t.Errorf("got %q, want %q", v, c.want)
+			}
+		})
+	}
+}
+
+// TestGitDiffAndListTree checks the parsers with the output of real git.
+func TestGitDiffAndListTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not found")
+	}
+
+	dir := t.TempDir()
+
+	mustGit := func(args ...string) string {
+		t.Helper()
+
+		v, err := runGit(dir, args...)
+		if err != nil {
+			t.Fatalf("git %v failed, err:%v", args, err)
+		}
+
+		return string(v)
+	}
+
+	writeFile := func(name, content string) {
+		t.Helper()
+
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit := func() string {
+		t.Helper()
+
+		mustGit("add", "-A")
+		mustGit(
+			"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "-q", "-m", "test",
+		)
+
+		v, err := gitLastCommit(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return v
+	}
+
+	mustGit("init", "-q")
+
+	writeFile("a b.txt", "hello")
+	writeFile("old", "old")
+	start := commit()
+
+	writeFile("a b.txt", "hello world")
+	writeFile("dir/文件", "")
+	if err := os.Remove(filepath.Join(dir, "old")); err != nil {
+		t.Fatal(err)
+	}
+	end := commit()
+
+	changes, err := gitDiff(dir, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantChanges := []fileChange{
+		{status: "M", path: "a b.txt"},
+		{status: "A", path: "dir/文件"},
+		{status: "D", path: "old"},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("diff: got %+v, want %+v", changes, wantChanges)
+	}
+
+	entries, err := gitListTree(dir, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("ls-tree: got %+v, want 2 entries", entries)
+	}
+
+	sizes := map[string]int64{}
+	oids := make([]string, len(entries))
+	for i := range entries {
+		sizes[entries[i].path] = entries[i].size
+		oids[i] = entries[i].oid
+	}
+
+	wantSizes := map[string]int64{"a b.txt": 11, "dir/文件": 0}
+	if !reflect.DeepEqual(sizes, wantSizes) {
+		t.Errorf("ls-tree: got %v, want %v", sizes, wantSizes)
+	}
+
+	blobs, err := gitReadBlobs(dir, oids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range entries {
+		if n := int64(len(blobs[entries[i].oid])); n != entries[i].size {
+			t.Errorf("blob of %s: got %d bytes, want %d", entries[i].path, n, entries[i].size)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package syncengineimpl
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The pointer file of lfs is less than 1024 bytes.
+	maxLFSPointerSize = 1024
+
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+)
+
+var reLFSOid = regexp.MustCompile(`^oid sha256:([0-9a-f]{64})$`)
+
+type lfsPointer struct {
+	sha  string
+	size int64
+}
+
+// parseLFSPointer parses the file and returns false if it is not a lfs pointer.
+func parseLFSPointer(file string, info os.FileInfo) (p lfsPointer, ok bool, err error) {
+	if info.Size() > maxLFSPointerSize {
+		return
+	}
+
+	v, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	p, ok = parseLFSPointerData(v)
+
+	return
+}
+
+// parseLFSPointerData requires the version line first, and both
+// the oid and size lines. The other keys are ignored.
+func parseLFSPointerData(v []byte) (p lfsPointer, ok bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(v))
+	if !scanner.Scan() || scanner.Text() != lfsPointerVersion {
+		return
+	}
+
+	hasOid, hasSize := false, false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := reLFSOid.FindStringSubmatch(line); len(m) == 2 {
+			p.sha = m[1]
+			hasOid = true
+
+			continue
+		}
+
+		if s := strings.TrimPrefix(line, "size "); s != line {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return
+			}
+
+			p.size = n
+			hasSize = true
+		}
+	}
+
+	ok = hasOid && hasSize
+
+	return
+}
@@ -0,0 +1,80 @@
+package syncengineimpl
+
+import "testing"
+
+func TestParseLFSPointerData(t *testing.T) {
+	const (
+		version = "version https://git-lfs.github.com/spec/v1\n"
+		sha     = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+		oid     = "oid sha256:" + sha + "\n"
+	)
+
+	cases := []struct {
+		name   string
+		in     string
+		want   lfsPointer
+		wantOK bool
+	}{
+		{
+			name:   "pointer",
+			in:     version + oid + "size 12345\n",
+			want:   lfsPointer{sha: sha, size: 12345},
+			wantOK: true,
+		},
+		{
+			name:   "extension keys",
+			in:     version + "ext-0-foo sha256:" + sha + "\n" + oid + "size 0\n",
+			want:   lfsPointer{sha: sha, size: 0},
+			wantOK: true,
+		},
+		{
+			name: "missing version",
+			in:   oid + "size 12345\n",
+		},
+		{
+			name: "version is not the first line",
+			in:   oid + version + "size 12345\n",
+		},
+		{
+			name: "unknown version",
+			in:   "version https://example.com/spec/v2\n" + oid + "size 12345\n",
+		},
+		{
+			name: "missing oid",
+			in:   version + "size 12345\n",
+		},
+		{
+			name: "invalid oid",
+			in:   version + "oid sha256:1234\nsize 12345\n",
+		},
+		{
+			name: "missing size",
+			in:   version + oid,
+		},
+		{
+			name: "invalid size",
+			in:   version + oid + "size -1\n",
+		},
+		{
+			name: "plain text",
+			in:   "hello world\n",
+		},
+		{
+			name: "empty",
+			in:   "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, ok := parseLFSPointerData([]byte(c.in))
+			if ok != c.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, c.wantOK)
+			}
+
+			if ok && p != c.want {
+				t.Errorf("got %+v, want %+v", p, c.want)
+			}
+		})
+	}
+}
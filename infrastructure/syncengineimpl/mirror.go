@@ -0,0 +1,255 @@
+package syncengineimpl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const mirrorSuffix = ".git"
+
+type mirror struct {
+	key string
+	dir string
+
+	// lock serializes the updating of mirror.
+	lock sync.Mutex
+
+	// the fields below are protected by the lock of mirrorCache.
+	size     int64
+	refs     int
+	lastUsed time.Time
+
+	// evicted means the mirror will be removed once it is released.
+	evicted bool
+}
+
+// mirrorCache keeps a bare mirror for each repo, so that the sync only
+// needs to fetch the new commits. The mirrors which are least recently
+// used will be evicted when the total size exceeds the budget.
+type mirrorCache struct {
+	dir    string
+	budget int64
+
+	lock    sync.Mutex
+	total   int64
+	mirrors map[string]*mirror
+}
+
+func newMirrorCache(dir string, budget int64) (*mirrorCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &mirrorCache{
+		dir:     dir,
+		budget:  budget,
+		mirrors: map[string]*mirror{},
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load restores the mirrors left by the last run.
+func (c *mirrorCache) load() error {
+	owners, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+
+		items, err := ioutil.ReadDir(filepath.Join(c.dir, owner.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			name := item.Name()
+			if !item.IsDir() || !strings.HasSuffix(name, mirrorSuffix) {
+				continue
+			}
+
+			key := owner.Name() + "/" + strings.TrimSuffix(name, mirrorSuffix)
+			m := c.newMirror(key)
+			m.size = dirSize(m.dir)
+			m.lastUsed = item.ModTime()
+
+			c.mirrors[key] = m
+			c.total += m.size
+		}
+	}
+
+	return nil
+}
+
+func (c *mirrorCache) newMirror(key string) *mirror {
+	return &mirror{
+		key: key,
+		dir: filepath.Join(c.dir, filepath.Clean("/"+key)+mirrorSuffix),
+	}
+}
+
+// acquire returns the mirror of repo which will not be evicted
+// until it is released.
+func (c *mirrorCache) acquire(key string) *mirror {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m, ok := c.mirrors[key]
+	if !ok {
+		m = c.newMirror(key)
+		c.mirrors[key] = m
+	}
+
+	m.refs++
+	m.lastUsed = time.Now()
+
+	return m
+}
+
+func (c *mirrorCache) release(m *mirror) {
+	size := dirSize(m.dir)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.total += size - m.size
+	m.size = size
+	m.refs--
+	m.lastUsed = time.Now()
+
+	if m.evicted && m.refs == 0 {
+		if err := c.removeMirror(m); err != nil {
+			logrus.Errorf(
+				"remove mirror of repo(%s) failed, err:%s", m.key, err.Error(),
+			)
+		}
+	}
+
+	c.evict()
+}
+
+// remove removes the mirror of repo. The mirror in use is
+// removed after it is released.
+func (c *mirrorCache) remove(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m, ok := c.mirrors[key]
+	if !ok {
+		return nil
+	}
+
+	if m.refs > 0 {
+		m.evicted = true
+
+		return nil
+	}
+
+	return c.removeMirror(m)
+}
+
+func (c *mirrorCache) removeMirror(m *mirror) error {
+	if err := os.RemoveAll(m.dir); err != nil {
+		return err
+	}
+
+	c.total -= m.size
+	delete(c.mirrors, m.key)
+
+	return nil
+}
+
+// evict removes the least recently used mirrors which are not in use
+// until the total size is within the budget.
+func (c *mirrorCache) evict() {
+	if c.total <= c.budget {
+		return
+	}
+
+	items := make([]*mirror, 0, len(c.mirrors))
+	for _, m := range c.mirrors {
+		if m.refs == 0 {
+			items = append(items, m)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].lastUsed.Before(items[j].lastUsed)
+	})
+
+	for _, m := range items {
+		if c.total <= c.budget {
+			return
+		}
+
+		if err := c.removeMirror(m); err != nil {
+			logrus.Errorf(
+				"evict mirror of repo(%s) failed, err:%s", m.key, err.Error(),
+			)
+
+			continue
+		}
+
+		logrus.Debugf("evict mirror of repo(%s), size=%d", m.key, m.size)
+	}
+}
+
+// update clones the mirror if it does not exist, otherwise fetches
+// the new commits. The mirror will be recreated only if it is broken,
+// because fetching may fail for a while, such as the network is down.
+func (m *mirror) update(url string, cred *platform.Credential) error {
+	if _, err := os.Stat(m.dir); err == nil {
+		if err = gitFetchMirror(m.dir, url, cred); err == nil {
+			return nil
+		}
+
+		err1 := gitCheckMirror(m.dir)
+		if err1 == nil {
+			return err
+		}
+
+		logrus.Warnf(
+			"mirror of repo(%s) is broken, recreate it. fetch err:%s, check err:%s",
+			m.key, err.Error(), err1.Error(),
+		)
+	}
+
+	if err := os.RemoveAll(m.dir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.dir), 0755); err != nil {
+		return err
+	}
+
+	return gitCloneMirror(url, m.dir, cred)
+}
+
+func dirSize(dir string) (n int64) {
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			n += info.Size()
+		}
+
+		return nil
+	})
+
+	return
+}
@@ -0,0 +1,84 @@
+package syncengineimpl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func TestMirrorUpdate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not found")
+	}
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin")
+
+	mustGit := func(args ...string) {
+		t.Helper()
+
+		if _, err := runGit(origin, args...); err != nil {
+			t.Fatalf("git %v failed, err:%v", args, err)
+		}
+	}
+
+	if err := os.MkdirAll(origin, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	mustGit("init", "-q")
+	mustGit(
+		"-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "-q", "--allow-empty", "-m", "test",
+	)
+
+	m := &mirror{key: "owner/1", dir: filepath.Join(root, "mirrors", "owner", "1.git")}
+	cred := &platform.Credential{}
+
+	// the marker is removed if the mirror is recreated.
+	marker := filepath.Join(m.dir, "marker")
+	hasMarker := func() bool {
+		_, err := os.Stat(marker)
+
+		return err == nil
+	}
+
+	if err := m.update(origin, cred); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("fetch failed", func(t *testing.T) {
+		if err := m.update(filepath.Join(root, "missing"), cred); err == nil {
+			t.Error("want the error of fetching")
+		}
+
+		if !hasMarker() {
+			t.Error("the mirror is recreated, but it is not broken")
+		}
+	})
+
+	t.Run("broken mirror", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(m.dir, "HEAD")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.update(origin, cred); err != nil {
+			t.Fatal(err)
+		}
+
+		if hasMarker() {
+			t.Error("the broken mirror is not recreated")
+		}
+
+		if err := gitCheckMirror(m.dir); err != nil {
+			t.Errorf("the recreated mirror is broken, err:%v", err)
+		}
+	})
+}
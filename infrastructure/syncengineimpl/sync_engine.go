@@ -0,0 +1,391 @@
+package syncengineimpl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/obs"
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+// NewSyncEngine creates the sync engine which keeps the mirrors of repos
+// under mirrorDir. The total size of mirrors is limited by budget bytes.
+func NewSyncEngine(s obs.OBS, mirrorDir string, budget int64) (
+	syncengine.SyncEngine, error,
+) {
+	c, err := newMirrorCache(mirrorDir, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syncEngine{
+		obsService: s,
+		mirrors:    c,
+	}, nil
+}
+
+type syncEngine struct {
+	obsService obs.OBS
+	mirrors    *mirrorCache
+}
+
+func (e *syncEngine) Sync(ctx context.Context, opt *syncengine.SyncOption) (
+	r syncengine.SyncResult, err error,
+) {
+	repoDir := filepath.Join(opt.WorkDir, "repo")
+	repoType := domain.RepoTypeOfPath(opt.RepoKey)
+
+	m := e.mirrors.acquire(opt.RepoKey)
+	defer e.mirrors.release(m)
+
+	start := time.Now()
+	err = e.clone(m, opt, repoDir)
+	metrics.ObserveStage(metrics.StageClone, repoType, start, err)
+	if err != nil {
+		return
+	}
+
+	start = time.Now()
+	changes, err := e.diff(repoDir, opt, &r)
+	metrics.ObserveStage(metrics.StageDiff, repoType, start, err)
+	if err != nil {
+		return
+	}
+
+	start = time.Now()
+	for i := range changes {
+		if isReserved(changes[i].path, opt.ReservedPaths) {
+			continue
+		}
+
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		if err = e.handleChange(repoDir, opt.OBSPath, &changes[i], &r); err != nil {
+			break
+		}
+	}
+	metrics.ObserveStage(metrics.StageUpload, repoType, start, err)
+
+	metrics.AddFiles(repoType, metrics.OpUpload, len(r.Added)+len(r.Modified))
+	metrics.AddFiles(repoType, metrics.OpDelete, len(r.Deleted))
+	metrics.AddBytes(repoType, metrics.OpUpload, r.UploadedBytes)
+
+	return
+}
+
+func (e *syncEngine) clone(m *mirror, opt *syncengine.SyncOption, repoDir string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.update(opt.CloneURL, &opt.Credential); err != nil {
+		return err
+	}
+
+	return gitCloneLocal(m.dir, opt.Ref, repoDir)
+}
+
+func (e *syncEngine) diff(
+	repoDir string, opt *syncengine.SyncOption, r *syncengine.SyncResult,
+) ([]fileChange, error) {
+	last, err := gitLastCommit(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	r.LastCommit = last
+
+	if r.Branch, err = gitCurrentBranch(repoDir); err != nil {
+		return nil, err
+	}
+
+	start := opt.StartCommit
+	if start == last {
+		return nil, nil
+	}
+
+	if start == "" {
+		files, err := gitListFiles(repoDir, last)
+		if err != nil {
+			return nil, err
+		}
+
+		return toAddedChanges(files), nil
+	}
+
+	ok, err := gitIsAncestor(repoDir, start, last)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return gitDiff(repoDir, start, last)
+	}
+
+	r.NonFastForward = true
+
+	return e.reconcile(repoDir, last, opt)
+}
+
+// reconcile returns all the files of tree, as well as the objects which
+// are not in the tree any more, so that the obs will be same as the tree.
+func (e *syncEngine) reconcile(
+	repoDir, commit string, opt *syncengine.SyncOption,
+) ([]fileChange, error) {
+	files, err := gitListFiles(repoDir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := e.obsService.ListObjects(opt.OBSPath + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string]bool, len(files))
+	for _, f := range files {
+		tree[f] = true
+	}
+
+	changes := toAddedChanges(files)
+
+	for i := range objs {
+		p := strings.TrimPrefix(objs[i].Path, opt.OBSPath+"/")
+
+		if !tree[p] && !isReserved(p, opt.ReservedPaths) {
+			changes = append(changes, fileChange{
+				status: diffDeleted,
+				path:   p,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+func isReserved(p string, reserved []string) bool {
+	for _, v := range reserved {
+		if p == v || strings.HasPrefix(p, strings.TrimSuffix(v, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toAddedChanges(files []string) []fileChange {
+	r := make([]fileChange, len(files))
+	for i := range files {
+		r[i] = fileChange{
+			status: diffAdded,
+			path:   files[i],
+		}
+	}
+
+	return r
+}
+
+func (e *syncEngine) handleChange(
+	repoDir, obsPath string, c *fileChange, r *syncengine.SyncResult,
+) error {
+	dst := filepath.Join(obsPath, c.path)
+
+	if c.status == diffDeleted {
+		return e.deleteFile(dst, c.path, r)
+	}
+
+	file := filepath.Join(repoDir, c.path)
+
+	// the file must exist since it is in the diff of the checked out commit,
+	// otherwise it would never be synced after the last commit is saved.
+	info, err := os.Lstat(file)
+	if err != nil {
+		return fmt.Errorf("stat %s failed, err:%s", c.path, err.Error())
+	}
+
+	// ignore the symlink and submodule, but delete the object of
+	// the regular file which it was.
+	if !info.Mode().IsRegular() {
+		if c.status == diffTypeChanged {
+			return e.deleteFile(dst, c.path, r)
+		}
+
+		return nil
+	}
+
+	p, ok, err := parseLFSPointer(file, info)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		r.LFSFiles = append(r.LFSFiles, syncengine.LFSFile{
+			Path: c.path,
+			SHA:  p.sha,
+			Size: p.size,
+		})
+		r.LFSBytes += p.size
+
+		return nil
+	}
+
+	err = utils.Retry(func() error {
+		return e.obsService.SaveFile(dst, file)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.UploadedBytes += info.Size()
+
+	if c.status == diffAdded {
+		r.Added = append(r.Added, c.path)
+	} else {
+		r.Modified = append(r.Modified, c.path)
+	}
+
+	return nil
+}
+
+// deleteFile deletes the object of file at dst.
+func (e *syncEngine) deleteFile(dst, file string, r *syncengine.SyncResult) error {
+	err := utils.Retry(func() error {
+		return e.obsService.DeleteObject(dst)
+	})
+	if err == nil {
+		r.Deleted = append(r.Deleted, file)
+	}
+
+	return err
+}
+
+func (e *syncEngine) ListTree(opt *syncengine.TreeOption) (
+	[]syncengine.TreeFile, error,
+) {
+	m := e.mirrors.acquire(opt.RepoKey)
+	defer e.mirrors.release(m)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.update(opt.CloneURL, &opt.Credential); err != nil {
+		return nil, err
+	}
+
+	entries, err := gitListTree(m.dir, opt.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lfs pointers are the small files.
+	oids := []string{}
+	for i := range entries {
+		if entries[i].size <= maxLFSPointerSize {
+			oids = append(oids, entries[i].oid)
+		}
+	}
+
+	blobs, err := gitReadBlobs(m.dir, oids)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make([]syncengine.TreeFile, len(entries))
+	for i := range entries {
+		item := &entries[i]
+
+		r[i] = syncengine.TreeFile{
+			Path: item.path,
+			Size: item.size,
+		}
+
+		if v, ok := blobs[item.oid]; ok {
+			if p, ok := parseLFSPointerData(v); ok {
+				r[i].LFS = &syncengine.LFSFile{
+					Path: item.path,
+					SHA:  p.sha,
+					Size: p.size,
+				}
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (e *syncEngine) EvictMirror(repoKey string) error {
+	return e.mirrors.remove(repoKey)
+}
+
+func (e *syncEngine) UploadFiles(ctx context.Context, opt *syncengine.UploadOption) (
+	n int64, err error,
+) {
+	repoType := domain.RepoTypeOfPath(opt.RepoKey)
+
+	m := e.mirrors.acquire(opt.RepoKey)
+	defer e.mirrors.release(m)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.update(opt.CloneURL, &opt.Credential); err != nil {
+		return
+	}
+
+	entries, err := gitListTree(m.dir, opt.Commit)
+	if err != nil {
+		return
+	}
+
+	oids := make(map[string]*treeEntry, len(entries))
+	for i := range entries {
+		oids[entries[i].path] = &entries[i]
+	}
+
+	file := filepath.Join(opt.WorkDir, "blob")
+	defer os.Remove(file)
+
+	start := time.Now()
+	uploaded := 0
+
+	for _, p := range opt.Files {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		item, ok := oids[p]
+		if !ok {
+			err = fmt.Errorf("%s is not a file of commit", p)
+
+			break
+		}
+
+		if err = gitSaveBlob(m.dir, item.oid, file); err != nil {
+			break
+		}
+
+		err = utils.Retry(func() error {
+			return e.obsService.SaveFile(filepath.Join(opt.OBSPath, p), file)
+		})
+		if err != nil {
+			break
+		}
+
+		n += item.size
+		uploaded++
+	}
+	metrics.ObserveStage(metrics.StageUpload, repoType, start, err)
+
+	metrics.AddFiles(repoType, metrics.OpUpload, uploaded)
+	metrics.AddBytes(repoType, metrics.OpUpload, n)
+
+	return
+}
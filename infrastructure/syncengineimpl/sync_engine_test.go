@@ -0,0 +1,97 @@
+package syncengineimpl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/syncengine"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/localobsimpl"
+)
+
+func TestHandleChange(t *testing.T) {
+	repoDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(repoDir, "file"), []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("file", filepath.Join(repoDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the submodule is checked out as an empty directory.
+	if err := os.Mkdir(filepath.Join(repoDir, "module"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		change fileChange
+		want   syncengine.SyncResult
+		// wantExists is whether the object exists after the change.
+		wantExists bool
+	}{
+		{
+			name:   "file to symlink",
+			change: fileChange{status: diffTypeChanged, path: "link"},
+			want:   syncengine.SyncResult{Deleted: []string{"link"}},
+		},
+		{
+			name:   "file to submodule",
+			change: fileChange{status: diffTypeChanged, path: "module"},
+			want:   syncengine.SyncResult{Deleted: []string{"module"}},
+		},
+		{
+			name:       "symlink to file",
+			change:     fileChange{status: diffTypeChanged, path: "file"},
+			want:       syncengine.SyncResult{Modified: []string{"file"}, UploadedBytes: 4},
+			wantExists: true,
+		},
+		{
+			name:       "symlink is modified",
+			change:     fileChange{status: "M", path: "link"},
+			wantExists: true,
+		},
+		{
+			name:   "file is deleted",
+			change: fileChange{status: diffDeleted, path: "deleted"},
+			want:   syncengine.SyncResult{Deleted: []string{"deleted"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := localobsimpl.NewOBS(&localobsimpl.Config{RootDir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := filepath.Join("repo", c.change.path)
+			if err := s.SaveObject(dst, "old"); err != nil {
+				t.Fatal(err)
+			}
+
+			e := &syncEngine{obsService: s}
+
+			var r syncengine.SyncResult
+			if err := e.handleChange(repoDir, "repo", &c.change, &r); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(r, c.want) {
+				t.Errorf("got %+v, want %+v", r, c.want)
+			}
+
+			objs, err := s.ListObjects(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (len(objs) > 0) != c.wantExists {
+				t.Errorf("got objects %v, want exists: %t", objs, c.wantExists)
+			}
+		})
+	}
+}
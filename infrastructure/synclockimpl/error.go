@@ -31,6 +31,9 @@ func convertError(err error) (out error) {
 	case errorDataNotExists:
 		out = synclock.NewErrorRepoNotExists(err)
 
+	case errorConcurrentUpdating:
+		out = synclock.NewErrorConcurrentUpdating(err)
+
 	default:
 		out = err
 	}
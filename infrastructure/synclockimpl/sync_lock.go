@@ -8,7 +8,8 @@ import (
 type SyncLockMapper interface {
 	Insert(*RepoSyncLockDO) (string, error)
 	Update(*RepoSyncLockDO) error
-	Get(string, string) (RepoSyncLockDO, error)
+	UpdateOwner(do *RepoSyncLockDO, owner string) error
+	Get(owner, platform, repoId string) (RepoSyncLockDO, error)
 }
 
 func NewRepoSyncLock(mapper SyncLockMapper) synclock.RepoSyncLock {
@@ -44,10 +45,26 @@ func (impl syncLock) Save(p *domain.RepoSyncLock) (r domain.RepoSyncLock, err er
 	return
 }
 
-func (impl syncLock) Find(owner domain.Account, repoId string) (
+func (impl syncLock) ChangeOwner(p *domain.RepoSyncLock, owner domain.Account) (
 	r domain.RepoSyncLock, err error,
 ) {
-	v, err := impl.mapper.Get(owner.Account(), repoId)
+	do := impl.toRepoSyncLockDO(p)
+
+	if err = impl.mapper.UpdateOwner(&do, owner.Account()); err != nil {
+		err = convertError(err)
+	} else {
+		r = *p
+		r.Owner = owner
+		r.Version += 1
+	}
+
+	return
+}
+
+func (impl syncLock) Find(owner domain.Account, platform, repoId string) (
+	r domain.RepoSyncLock, err error,
+) {
+	v, err := impl.mapper.Get(owner.Account(), platform, repoId)
 	if err != nil {
 		err = convertError(err)
 	} else {
@@ -59,12 +76,19 @@ func (impl syncLock) Find(owner domain.Account, repoId string) (
 
 func (impl syncLock) toRepoSyncLockDO(p *domain.RepoSyncLock) RepoSyncLockDO {
 	return RepoSyncLockDO{
-		Id:         p.Id,
-		Owner:      p.Owner.Account(),
-		RepoId:     p.RepoId,
-		LastCommit: p.LastCommit,
-		Status:     p.Status.RepoSyncStatus(),
-		Version:    p.Version,
+		Id:          p.Id,
+		Owner:       p.Owner.Account(),
+		RepoId:      p.RepoId,
+		Platform:    p.Platform,
+		LastCommit:  p.LastCommit,
+		Branch:      p.Branch,
+		Status:      p.Status.RepoSyncStatus(),
+		Version:     p.Version,
+		Holder:      p.Holder,
+		StartedAt:   p.StartedAt,
+		HeartbeatAt: p.HeartbeatAt,
+
+		NonFastForwardAt: p.NonFastForwardAt,
 	}
 }
 
@@ -72,17 +96,31 @@ type RepoSyncLockDO struct {
 	Id         string
 	Owner      string
 	RepoId     string
+	Platform   string
 	Status     string
 	RepoType   string
 	LastCommit string
+	Branch     string
 	Version    int
+
+	Holder      string
+	StartedAt   int64
+	HeartbeatAt int64
+
+	NonFastForwardAt int64
 }
 
 func (do *RepoSyncLockDO) toSyncLock(r *domain.RepoSyncLock) (err error) {
 	r.Id = do.Id
 	r.RepoId = do.RepoId
+	r.Platform = do.Platform
 	r.Version = do.Version
 	r.LastCommit = do.LastCommit
+	r.Branch = do.Branch
+	r.Holder = do.Holder
+	r.StartedAt = do.StartedAt
+	r.HeartbeatAt = do.HeartbeatAt
+	r.NonFastForwardAt = do.NonFastForwardAt
 
 	if r.Owner, err = domain.NewAccount(do.Owner); err != nil {
 		return
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -19,11 +21,22 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain/dedup"
+	"github.com/opensourceways/xihe-sync-repo/domain/obs"
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/dedupimpl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/electionimpl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/localobsimpl"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/mysql"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/obsimpl"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/platformimpl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/retrytaskimpl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/s3impl"
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/syncengineimpl"
 	"github.com/opensourceways/xihe-sync-repo/infrastructure/synclockimpl"
+	"github.com/opensourceways/xihe-sync-repo/server"
 	"github.com/opensourceways/xihe-sync-repo/syncrepo"
+	"github.com/opensourceways/xihe-sync-repo/utils"
 )
 
 type options struct {
@@ -60,8 +73,17 @@ const component = "xihe-sync-repo"
 
 func main() {
 	logrusutil.ComponentInit(component)
+	logrus.SetFormatter(&utils.RedactFormatter{
+		Formatter: logrus.StandardLogger().Formatter,
+	})
 	log := logrus.NewEntry(logrus.StandardLogger())
 
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(log, os.Args[2:]))
+		}
+	}
+
 	o, err := gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
 
 	if err != nil {
@@ -103,25 +125,76 @@ func main() {
 		return
 	}
 
-	// gitlab
-	gitlab, err := platformimpl.NewPlatform(&cfg.Gitlab)
+	s, err := initServices(&cfg, log, filepath.Join(cfg.App.WorkDir, "mirrors"))
 	if err != nil {
-		log.Errorf("init gitlab platform failed, err:%s", err.Error())
+		log.Errorf("init services failed, err:%s", err.Error())
+
+		return
+	}
+
+	retryTaskRepo := retrytaskimpl.NewRetryTaskRepo(mysql.NewRetryTaskMapper())
+
+	d := syncrepo.NewSyncRepo(
+		&cfg.SyncRepo, s.service, retryTaskRepo, newEventDedup(&cfg.SyncRepo.Dedup),
+	)
+	if err != nil {
+		log.Errorf("Error new dispatcherj, err:%s", err.Error())
 
 		return
 	}
 
+	srv := server.NewServer(&cfg.Server, log, s.service, d)
+
+	// reconciler, only the repos on gitlab are reconciled.
+	var lister platform.RepoLister
+	if p, ok := s.platforms[platform.Gitlab]; ok {
+		if lister, ok = p.(platform.RepoLister); !ok {
+			log.Error("the platform can't list repos")
+
+			return
+		}
+	}
+
+	elector := electionimpl.NewElector(mysql.NewLeaderLeaseMapper())
+
+	reconciler := syncrepo.NewReconciler(
+		&cfg.SyncRepo.Reconcile, cfg.App.Instance, d, s.service, lister, elector,
+	)
+
+	cleaner := syncrepo.NewTrashCleaner(
+		&cfg.SyncRepo, cfg.App.Instance, s.service, elector,
+	)
+
+	// run
+	run(d, srv, reconciler, cleaner, log)
+}
+
+// services are shared by the daemon and the subcommands.
+type services struct {
+	platforms map[string]platform.Platform
+	service   app.SyncService
+}
+
+// initServices creates the services. The mirrors of repos are kept
+// in mirrorDir which should not be shared by the processes.
+func initServices(cfg *configuration, log *logrus.Entry, mirrorDir string) (
+	s services, err error,
+) {
+	if s.platforms, err = newPlatforms(cfg); err != nil {
+		return
+	}
+
 	// obs service
-	obsService, err := obsimpl.NewOBS(&cfg.OBS)
+	obsService, err := newOBS(cfg)
 	if err != nil {
-		log.Errorf("init obs service failed, err:%s", err.Error())
+		err = fmt.Errorf("init obs service failed, err:%s", err.Error())
 
 		return
 	}
 
 	// mysql
-	if err := mysql.Init(&cfg.Mysql); err != nil {
-		log.Errorf("init mysql failed, err:%s", err.Error())
+	if err = mysql.Init(&cfg.Mysql); err != nil {
+		err = fmt.Errorf("init mysql failed, err:%s", err.Error())
 
 		return
 	}
@@ -129,17 +202,79 @@ func main() {
 	lock := synclockimpl.NewRepoSyncLock(mysql.NewSyncLockMapper())
 
 	// sync service
-	service := app.NewSyncService(&cfg.App, log, obsService, gitlab, lock)
-
-	d := syncrepo.NewSyncRepo(&cfg.SyncRepo, service)
+	engine, err := syncengineimpl.NewSyncEngine(
+		obsService, mirrorDir, cfg.SyncRepo.MirrorCacheSize(),
+	)
 	if err != nil {
-		log.Errorf("Error new dispatcherj, err:%s", err.Error())
+		err = fmt.Errorf("init sync engine failed, err:%s", err.Error())
 
 		return
 	}
 
-	// run
-	run(d, log)
+	ws := cfg.SyncRepo.Workspace()
+
+	s.service = app.NewSyncService(
+		&cfg.App, log, obsService, s.platforms, lock, engine, &ws,
+	)
+
+	return
+}
+
+func newPlatforms(cfg *configuration) (map[string]platform.Platform, error) {
+	r := map[string]platform.Platform{}
+
+	if cfg.Gitlab != nil {
+		p, err := platformimpl.NewPlatform(cfg.Gitlab)
+		if err != nil {
+			return nil, fmt.Errorf("init gitlab platform failed, err:%s", err.Error())
+		}
+
+		r[platform.Gitlab] = p
+	}
+
+	if cfg.Github != nil {
+		r[platform.Github] = platformimpl.NewGithubPlatform(cfg.Github)
+	}
+
+	if cfg.Gitea != nil {
+		r[platform.Gitea] = platformimpl.NewGiteaPlatform(cfg.Gitea)
+	}
+
+	if cfg.Gitee != nil {
+		p, err := platformimpl.NewGiteePlatform(cfg.Gitee)
+		if err != nil {
+			return nil, fmt.Errorf("init gitee platform failed, err:%s", err.Error())
+		}
+
+		r[platform.Gitee] = p
+	}
+
+	if cfg.Git != nil {
+		r[platform.Git] = platformimpl.NewGitPlatform(cfg.Git)
+	}
+
+	return r, nil
+}
+
+func newEventDedup(cfg *syncrepo.DedupConfig) dedup.EventDedup {
+	if cfg.Store == syncrepo.DedupStoreMysql {
+		return dedupimpl.NewEventDedup(mysql.NewEventMapper(), cfg.TTL)
+	}
+
+	return dedupimpl.NewMemoryEventDedup(cfg.TTL, cfg.Size)
+}
+
+func newOBS(cfg *configuration) (obs.OBS, error) {
+	switch cfg.Storage {
+	case storageLocal:
+		return localobsimpl.NewOBS(cfg.LocalOBS)
+
+	case storageS3:
+		return s3impl.NewOBS(cfg.S3)
+
+	default:
+		return obsimpl.NewOBS(cfg.OBS)
+	}
 }
 
 func connetKafka(cfg *mq.MQConfig) error {
@@ -203,7 +338,11 @@ func parseAddress(addresses string) []string {
 	return r
 }
 
-func run(d *syncrepo.SyncRepo, log *logrus.Entry) {
+func run(
+	d *syncrepo.SyncRepo, srv *server.Server,
+	reconciler *syncrepo.Reconciler, cleaner *syncrepo.TrashCleaner,
+	log *logrus.Entry,
+) {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
@@ -237,6 +376,33 @@ func run(d *syncrepo.SyncRepo, log *logrus.Entry) {
 		}
 	}(ctx)
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// exit if the server fails, so that the pod will be restarted,
+		// instead of running without the metrics and admin api.
+		if err := srv.Run(ctx); err != nil {
+			log.Errorf("run server failed, err:%v", err)
+
+			done()
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		reconciler.Run(ctx, log)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		cleaner.Run(ctx, log)
+	}()
+
 	if err := d.Run(ctx, log); err != nil {
 		log.Errorf("subscribe failed, err:%v", err)
 	}
@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "xihe_sync_repo"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	// The stages are the values of label stage of stage_duration_seconds,
+	// and the operations are the values of label operation of files_total
+	// and bytes_total. The lfs copy is both a stage and an operation.
+	StageClone      = "clone"
+	StageDiff       = "diff"
+	StageUpload     = "small_file_upload"
+	StageLFSCopy    = "lfs_copy"
+	StageLockSave   = "lock_save"
+	StageSaveCommit = "save_commit"
+
+	OpUpload  = "upload"
+	OpDelete  = "delete"
+	OpLFSCopy = "lfs_copy"
+
+	RetryScheduled   = "scheduled"
+	RetryQuarantined = "quarantined"
+
+	RejectMissingSignature = "missing_signature"
+	RejectInvalidSignature = "invalid_signature"
+)
+
+var (
+	stageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stage_duration_seconds",
+			Help:      "The duration of each stage of sync.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 16),
+		},
+		[]string{"stage", "repo_type", "result"},
+	)
+
+	syncs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "syncs_total",
+			Help:      "The number of syncs.",
+		},
+		[]string{"repo_type", "result"},
+	)
+
+	failures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sync_failures_total",
+			Help:      "The number of failed syncs by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	files = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "files_total",
+			Help:      "The number of files uploaded, deleted or copied from lfs store.",
+		},
+		[]string{"repo_type", "operation"},
+	)
+
+	bytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_total",
+			Help:      "The bytes of files uploaded or copied from lfs store.",
+		},
+		[]string{"repo_type", "operation"},
+	)
+
+	busyWorkers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "busy_workers",
+			Help:      "The number of workers which are handling tasks.",
+		},
+	)
+
+	retries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "The number of failed events put back to the retry queue.",
+		},
+		[]string{"result"},
+	)
+
+	coalescedTasks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "coalesced_tasks_total",
+			Help:      "The number of sync tasks collapsed into the pending one of the same repo.",
+		},
+	)
+
+	rejectedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rejected_events_total",
+			Help:      "The number of events which failed the verification.",
+		},
+		[]string{"topic", "reason"},
+	)
+
+	reservedBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workspace_reserved_bytes",
+			Help:      "The bytes of workspace reserved by the running syncs.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		stageDuration, syncs, failures, files, bytes, busyWorkers, retries,
+		coalescedTasks, rejectedEvents, reservedBytes,
+	)
+}
+
+func result(err error) string {
+	if err == nil {
+		return ResultSuccess
+	}
+
+	return ResultFailure
+}
+
+// ObserveStage records the duration of stage started at start.
+func ObserveStage(stage, repoType string, start time.Time, err error) {
+	stageDuration.WithLabelValues(stage, repoType, result(err)).Observe(
+		time.Since(start).Seconds(),
+	)
+}
+
+func IncSync(repoType string, err error) {
+	syncs.WithLabelValues(repoType, result(err)).Inc()
+}
+
+func IncSyncFailure(reason string) {
+	failures.WithLabelValues(reason).Inc()
+}
+
+func AddFiles(repoType, op string, n int) {
+	files.WithLabelValues(repoType, op).Add(float64(n))
+}
+
+func AddBytes(repoType, op string, n int64) {
+	bytes.WithLabelValues(repoType, op).Add(float64(n))
+}
+
+func WorkerBusy() {
+	busyWorkers.Inc()
+}
+
+func WorkerIdle() {
+	busyWorkers.Dec()
+}
+
+func IncRetry(result string) {
+	retries.WithLabelValues(result).Inc()
+}
+
+func IncCoalescedTask() {
+	coalescedTasks.Inc()
+}
+
+func IncRejectedEvent(topic, reason string) {
+	rejectedEvents.WithLabelValues(topic, reason).Inc()
+}
+
+func ReserveWorkspace(n int64) {
+	reservedBytes.Add(float64(n))
+}
+
+func ReleaseWorkspace(n int64) {
+	reservedBytes.Sub(float64(n))
+}
+
+// RegisterQueueOccupancy reports the number of tasks waiting in the queue.
+func RegisterQueueOccupancy(f func() float64) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "message_chan_occupancy",
+			Help:      "The number of tasks waiting in the message channel.",
+		},
+		f,
+	))
+}
+
+// RegisterPendingTasks reports the number of tasks waiting for the running
+// task of the same repo, which have been taken out of the queue.
+func RegisterPendingTasks(f func() float64) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pending_tasks",
+			Help:      "The number of tasks waiting for the running task of the same repo.",
+		},
+		f,
+	))
+}
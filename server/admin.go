@@ -0,0 +1,323 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+	"github.com/opensourceways/xihe-sync-repo/domain/synclock"
+)
+
+const adminPrefix = "/api/v1/admin/"
+
+func (s *Server) adminRouter() *router {
+	rt := &router{prefix: adminPrefix}
+
+	rt.add(http.MethodPost, "repos/{}/{}/sync", s.triggerSync)
+	rt.add(http.MethodGet, "repos/{}/{}/lock", s.getSyncLock)
+	rt.add(http.MethodPost, "repos/{}/{}/unlock", s.unlock)
+	rt.add(http.MethodPost, "repos/{}/{}/reset", s.resetLastCommit)
+	rt.add(http.MethodGet, "repos/{}/{}/verify", s.verify)
+	rt.add(http.MethodPost, "repos/{}/{}/repair", s.repair)
+
+	rt.add(http.MethodGet, "quarantine", s.listQuarantined)
+	rt.add(http.MethodPost, "quarantine/{}/replay", s.replay)
+
+	return rt
+}
+
+type syncRequest struct {
+	RepoName string `json:"repo_name"`
+
+	// Platform is the platform which the repo is hosted on.
+	// It is gitlab if empty.
+	Platform string `json:"platform"`
+}
+
+type repairRequest struct {
+	RepoName string `json:"repo_name"`
+	Platform string `json:"platform"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+type syncLockView struct {
+	Owner       string `json:"owner"`
+	RepoId      string `json:"repo_id"`
+	Platform    string `json:"platform"`
+	Status      string `json:"status"`
+	Version     int    `json:"version"`
+	LastCommit  string `json:"last_commit"`
+	Branch      string `json:"branch"`
+	Holder      string `json:"holder"`
+	StartedAt   int64  `json:"started_at"`
+	HeartbeatAt int64  `json:"heartbeat_at"`
+
+	NonFastForwardAt int64 `json:"non_fast_forward_at"`
+}
+
+func toSyncLockView(c *domain.RepoSyncLock) syncLockView {
+	v := syncLockView{
+		Owner:       c.Owner.Account(),
+		RepoId:      c.RepoId,
+		Platform:    c.Platform,
+		Version:     c.Version,
+		LastCommit:  c.LastCommit,
+		Branch:      c.Branch,
+		Holder:      c.Holder,
+		StartedAt:   c.StartedAt,
+		HeartbeatAt: c.HeartbeatAt,
+
+		NonFastForwardAt: c.NonFastForwardAt,
+	}
+
+	if c.Status != nil {
+		v.Status = c.Status.RepoSyncStatus()
+	}
+
+	return v
+}
+
+type retryTaskView struct {
+	Id          string `json:"id"`
+	EventId     string `json:"event_id"`
+	Topic       string `json:"topic"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error"`
+	NextRetryAt int64  `json:"next_retry_at"`
+}
+
+func toRetryTaskView(t *domain.RetryTask) retryTaskView {
+	return retryTaskView{
+		Id:          t.Id,
+		EventId:     t.EventId,
+		Topic:       t.Topic,
+		Attempts:    t.Attempts,
+		LastError:   t.LastError,
+		NextRetryAt: t.NextRetryAt,
+	}
+}
+
+func (s *Server) triggerSync(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	req := syncRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if req.RepoName == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing repo_name"))
+
+		return
+	}
+
+	err = s.dispatcher.Dispatch(&app.RepoInfo{
+		Owner:    owner,
+		RepoId:   params[1],
+		RepoName: req.RepoName,
+		Platform: req.Platform,
+	})
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+
+		return
+	}
+
+	writeData(w, http.StatusAccepted, "the sync task is added")
+}
+
+// getSyncLock, unlock and resetLastCommit accept the platform of repo,
+// such as /repos/{owner}/{repo_id}/lock?platform=github.
+// It is gitlab if empty.
+func (s *Server) getSyncLock(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	c, err := s.service.GetSyncLock(&app.RepoInfo{
+		Owner:    owner,
+		RepoId:   params[1],
+		Platform: r.URL.Query().Get("platform"),
+	})
+	if err != nil {
+		writeSyncLockError(w, err)
+
+		return
+	}
+
+	writeData(w, http.StatusOK, toSyncLockView(&c))
+}
+
+func (s *Server) unlock(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = s.service.Unlock(&app.RepoInfo{
+		Owner:    owner,
+		RepoId:   params[1],
+		Platform: r.URL.Query().Get("platform"),
+	})
+	if err != nil {
+		writeSyncLockError(w, err)
+
+		return
+	}
+
+	writeData(w, http.StatusOK, "unlocked")
+}
+
+func (s *Server) resetLastCommit(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = s.service.ResetLastCommit(&app.RepoInfo{
+		Owner:    owner,
+		RepoId:   params[1],
+		Platform: r.URL.Query().Get("platform"),
+	})
+	if err != nil {
+		writeSyncLockError(w, err)
+
+		return
+	}
+
+	writeData(w, http.StatusOK, "reset")
+}
+
+// verify requires the repo name which is used to clone the repo,
+// such as /repos/{owner}/{repo_id}/verify?repo_name=xxx
+func (s *Server) verify(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	name := query.Get("repo_name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing repo_name"))
+
+		return
+	}
+
+	report, err := s.service.Verify(&app.RepoInfo{
+		Owner:    owner,
+		RepoId:   params[1],
+		RepoName: name,
+		Platform: query.Get("platform"),
+	})
+	if err != nil {
+		writeSyncLockError(w, err)
+
+		return
+	}
+
+	writeData(w, http.StatusOK, report)
+}
+
+func (s *Server) repair(w http.ResponseWriter, r *http.Request, params []string) {
+	owner, err := domain.NewAccount(params[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	req := repairRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if req.RepoName == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing repo_name"))
+
+		return
+	}
+
+	report, err := s.service.Repair(
+		&app.RepoInfo{
+			Owner:    owner,
+			RepoId:   params[1],
+			RepoName: req.RepoName,
+			Platform: req.Platform,
+		},
+		req.DryRun,
+	)
+	if err != nil {
+		writeSyncLockError(w, err)
+
+		return
+	}
+
+	writeData(w, http.StatusOK, report)
+}
+
+func (s *Server) listQuarantined(w http.ResponseWriter, r *http.Request, params []string) {
+	tasks, err := s.dispatcher.QuarantinedTasks()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	v := make([]retryTaskView, len(tasks))
+	for i := range tasks {
+		v[i] = toRetryTaskView(&tasks[i])
+	}
+
+	writeData(w, http.StatusOK, v)
+}
+
+func (s *Server) replay(w http.ResponseWriter, r *http.Request, params []string) {
+	if err := s.dispatcher.ReplayTask(params[0]); err != nil {
+		if retrytask.IsErrorTaskNotExists(err) {
+			writeError(w, http.StatusNotFound, err)
+		} else {
+			writeError(w, http.StatusInternalServerError, err)
+		}
+
+		return
+	}
+
+	writeData(w, http.StatusOK, "replayed")
+}
+
+func writeSyncLockError(w http.ResponseWriter, err error) {
+	switch {
+	case synclock.IsRepoSyncLockNotExist(err):
+		writeError(w, http.StatusNotFound, err)
+
+	case synclock.IsErrorConcurrentUpdating(err), app.IsErrorRepoBusy(err),
+		app.IsErrorRepoNotSynced(err):
+		writeError(w, http.StatusConflict, err)
+
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
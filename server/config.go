@@ -0,0 +1,24 @@
+package server
+
+import "errors"
+
+type Config struct {
+	Port int `json:"port"`
+
+	// Token is used to authenticate the requests of admin api.
+	Token string `json:"token" required:"true"`
+}
+
+func (cfg *Config) SetDefault() {
+	if cfg.Port <= 0 {
+		cfg.Port = 8888
+	}
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.Port > 65535 {
+		return errors.New("invalid port")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const paramSegment = "{}"
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, params []string)
+
+// route matches the path segment by segment. The segment of pattern
+// which is paramSegment matches any value and is passed to the handler.
+type route struct {
+	method  string
+	pattern []string
+	handler handlerFunc
+}
+
+func (rt *route) match(method string, segments []string) ([]string, bool) {
+	if method != rt.method || len(segments) != len(rt.pattern) {
+		return nil, false
+	}
+
+	var params []string
+	for i, p := range rt.pattern {
+		if p == paramSegment {
+			params = append(params, segments[i])
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+type router struct {
+	prefix string
+	routes []route
+}
+
+func (rt *router) add(method, pattern string, h handlerFunc) {
+	rt.routes = append(rt.routes, route{
+		method:  method,
+		pattern: strings.Split(pattern, "/"),
+		handler: h,
+	})
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(
+		strings.Trim(strings.TrimPrefix(r.URL.Path, rt.prefix), "/"), "/",
+	)
+
+	for i := range rt.routes {
+		if params, ok := rt.routes[i].match(r.Method, segments); ok {
+			rt.routes[i].handler(w, r, params)
+
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, errors.New("not found"))
+}
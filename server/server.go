@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+// TaskDispatcher dispatches the tasks to the worker pool and manages
+// the retry queue.
+type TaskDispatcher interface {
+	Dispatch(*app.RepoInfo) error
+	QuarantinedTasks() ([]domain.RetryTask, error)
+	ReplayTask(id string) error
+}
+
+type Server struct {
+	cfg        Config
+	log        *logrus.Entry
+	service    app.SyncService
+	dispatcher TaskDispatcher
+}
+
+func NewServer(
+	cfg *Config, log *logrus.Entry,
+	service app.SyncService, dispatcher TaskDispatcher,
+) *Server {
+	return &Server{
+		cfg:        *cfg,
+		log:        log,
+		service:    service,
+		dispatcher: dispatcher,
+	}
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(adminPrefix, s.auth(s.adminRouter()))
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		c, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(c); err != nil {
+			s.log.Errorf("shutdown server failed, err:%s", err.Error())
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) auth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+type response struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+func writeData(w http.ResponseWriter, code int, data interface{}) {
+	writeResponse(w, code, response{Data: data})
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeResponse(w, code, response{Error: utils.Redact(err.Error())})
+}
+
+func writeResponse(w http.ResponseWriter, code int, v response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -2,34 +2,155 @@ package syncrepo
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
 )
 
+type TopicConfig struct {
+	Topic     string `json:"topic"       required:"true"`
+	UserAgent string `json:"user_agent"  required:"true"`
+
+	// Platform is the platform which the events of topic come from.
+	// It can be gitlab, github, gitea or gitee and is gitlab by default.
+	Platform string `json:"platform"`
+
+	Verify VerifyConfig `json:"verify"`
+}
+
+func (cfg *TopicConfig) setDefault() {
+	if cfg.Platform == "" {
+		cfg.Platform = platform.Gitlab
+	}
+
+	cfg.Verify.setDefault(cfg.Platform)
+}
+
+func (cfg *TopicConfig) validate() error {
+	if cfg.Topic == "" {
+		return errors.New("missing topic")
+	}
+
+	if cfg.UserAgent == "" {
+		return fmt.Errorf("missing user_agent of topic:%s", cfg.Topic)
+	}
+
+	if _, ok := generatorBuilders[cfg.Platform]; !ok {
+		return fmt.Errorf(
+			"unsupported platform:%s of topic:%s", cfg.Platform, cfg.Topic,
+		)
+	}
+
+	if err := cfg.Verify.validate(); err != nil {
+		return fmt.Errorf("%s of topic:%s", err.Error(), cfg.Topic)
+	}
+
+	return nil
+}
+
 type Config struct {
-	// AccessEndpoint is used to send back the message.
-	AccessEndpoint string `json:"access_endpoint"  required:"true"`
-	AccessHmac     string `json:"access_hmac"      required:"true"`
+	TopicConfig
 
-	Topic     string `json:"topic"                 required:"true"`
-	UserAgent string `json:"user_agent"            required:"true"`
+	// Topics are the topics subscribed besides the one above.
+	Topics []TopicConfig `json:"topics"`
 
 	// The unit is Gbyte
 	SizeOfWorspace int `json:"size_of_workspace"   required:"true"`
 
-	// The unit is Gbyte
+	// The unit is Gbyte. It is the estimated size of repo
+	// whose size can't be got from the platform.
 	AverageRepoSize int `json:"average_repo_size"  required:"true"`
+
+	// RepoSizeFactor is the ratio of the disk taken by the sync of a repo
+	// to the size of its git objects got from the platform, because the
+	// files checked out are uncompressed. It is 3 by default.
+	RepoSizeFactor int `json:"repo_size_factor"`
+
+	// Concurrency is the number of workers. Each sync reserves the disk
+	// of workspace by the size of repo, so it can be more than the default
+	// which is SizeOfWorspace / AverageRepoSize / 2.
+	Concurrency int `json:"concurrency"`
+
+	// RepoQueueSize is the max number of tasks of a repo which wait to be
+	// handled. The new task of the repo waits until there is room, unless
+	// it can be collapsed. It is 10 by default.
+	RepoQueueSize int `json:"repo_queue_size"`
+
+	Retry RetryConfig `json:"retry"`
+
+	Reconcile ReconcileConfig `json:"reconcile"`
+
+	Dedup DedupConfig `json:"dedup"`
+
+	// The unit is second. It is the interval to clean the trash.
+	TrashCleanInterval int `json:"trash_clean_interval"`
+}
+
+// AllTopics returns all the topics subscribed.
+func (cfg *Config) AllTopics() []TopicConfig {
+	return append([]TopicConfig{cfg.TopicConfig}, cfg.Topics...)
 }
 
 func (cfg *Config) concurrentSize() int {
+	if cfg.Concurrency > 0 {
+		return cfg.Concurrency
+	}
+
 	return cfg.SizeOfWorspace / (cfg.AverageRepoSize) / 2
 }
 
-func (cfg *Config) Validate() error {
-	if cfg.Topic == "" {
-		return errors.New("missing topic")
+// MirrorCacheSize returns the disk budget of the mirror cache in bytes.
+// The other half of workspace is used by the syncs.
+func (cfg *Config) MirrorCacheSize() int64 {
+	return int64(cfg.SizeOfWorspace) << 30 / 2
+}
+
+// Workspace returns the half of workspace used by the syncs.
+func (cfg *Config) Workspace() app.Workspace {
+	return app.Workspace{
+		Capacity:        int64(cfg.SizeOfWorspace)<<30 - cfg.MirrorCacheSize(),
+		DefaultRepoSize: int64(cfg.AverageRepoSize) << 30,
+		SizeFactor:      int64(cfg.RepoSizeFactor),
 	}
+}
 
-	if cfg.UserAgent == "" {
-		return errors.New("missing user_agent")
+func (cfg *Config) SetDefault() {
+	cfg.TopicConfig.setDefault()
+
+	for i := range cfg.Topics {
+		cfg.Topics[i].setDefault()
+	}
+
+	cfg.Retry.setDefault()
+	cfg.Reconcile.setDefault()
+	cfg.Dedup.setDefault()
+
+	if cfg.TrashCleanInterval <= 0 {
+		cfg.TrashCleanInterval = 3600
+	}
+
+	if cfg.RepoSizeFactor <= 0 {
+		cfg.RepoSizeFactor = 3
+	}
+
+	if cfg.RepoQueueSize <= 0 {
+		cfg.RepoQueueSize = 10
+	}
+}
+
+func (cfg *Config) Validate() error {
+	topics := map[string]bool{}
+	for _, item := range cfg.AllTopics() {
+		if err := item.validate(); err != nil {
+			return err
+		}
+
+		if topics[item.Topic] {
+			return fmt.Errorf("duplicate topic:%s", item.Topic)
+		}
+
+		topics[item.Topic] = true
 	}
 
 	if cfg.AverageRepoSize <= 0 {
@@ -40,5 +161,128 @@ func (cfg *Config) Validate() error {
 		return errors.New("the concurrent size <= 0")
 	}
 
+	if err := cfg.Retry.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Dedup.validate(); err != nil {
+		return err
+	}
+
+	return cfg.Reconcile.validate()
+}
+
+type RetryConfig struct {
+	// MaxAttempts is the max times to handle an event.
+	// The event will be quarantined after that.
+	MaxAttempts int `json:"max_attempts"`
+
+	// The unit is second. The delay of n-th retry is
+	// BaseDelay * 2^(n-1) with jitter and is limited by MaxDelay.
+	BaseDelay int `json:"base_delay"`
+	MaxDelay  int `json:"max_delay"`
+
+	// The unit is second
+	PollInterval int `json:"poll_interval"`
+
+	// The unit is second. The claimed task will be due again after
+	// ClaimTimeout in case the instance handling it exits abnormally.
+	ClaimTimeout int `json:"claim_timeout"`
+}
+
+func (cfg *RetryConfig) setDefault() {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 10
+	}
+
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 3600
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10
+	}
+
+	if cfg.ClaimTimeout <= 0 {
+		cfg.ClaimTimeout = 3600
+	}
+}
+
+func (cfg *RetryConfig) validate() error {
+	if cfg.BaseDelay > cfg.MaxDelay {
+		return errors.New("retry base_delay must not be greater than max_delay")
+	}
+
+	return nil
+}
+
+type ReconcileConfig struct {
+	// The unit is second. The reconciliation is disabled if it is 0.
+	Interval int `json:"interval"`
+
+	// RateLimit is the max number of repos checked per second.
+	RateLimit float64 `json:"rate_limit"`
+
+	// Owners are the owners whose repos will be reconciled.
+	// All the owners will be reconciled if it is empty.
+	Owners         []string `json:"owners"`
+	ExcludedOwners []string `json:"excluded_owners"`
+}
+
+func (cfg *ReconcileConfig) setDefault() {
+	if cfg.RateLimit <= 0 {
+		cfg.RateLimit = 5
+	}
+}
+
+func (cfg *ReconcileConfig) validate() error {
+	if cfg.Interval < 0 {
+		return errors.New("invalid reconcile interval")
+	}
+
+	return nil
+}
+
+const (
+	DedupStoreMemory = "memory"
+	DedupStoreMysql  = "mysql"
+)
+
+type DedupConfig struct {
+	// Store keeps the handled events. It can be memory or mysql and is
+	// memory by default. The memory one only drops the duplicate events
+	// delivered to the same instance.
+	Store string `json:"store"`
+
+	// The unit is second. It is how long the events are kept.
+	TTL int64 `json:"ttl"`
+
+	// Size is the max number of events kept in memory.
+	Size int `json:"size"`
+}
+
+func (cfg *DedupConfig) setDefault() {
+	if cfg.Store == "" {
+		cfg.Store = DedupStoreMemory
+	}
+
+	if cfg.TTL <= 0 {
+		cfg.TTL = 86400
+	}
+
+	if cfg.Size <= 0 {
+		cfg.Size = 10000
+	}
+}
+
+func (cfg *DedupConfig) validate() error {
+	if cfg.Store != DedupStoreMemory && cfg.Store != DedupStoreMysql {
+		return errors.New("invalid dedup store")
+	}
+
 	return nil
 }
@@ -0,0 +1,130 @@
+package syncrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const (
+	headerGiteaEvent    = "X-Gitea-Event"
+	headerGiteaDelivery = "X-Gitea-Delivery"
+
+	giteaEventPush   = "push"
+	giteaEventDelete = "delete"
+
+	giteaRefTypeTag = "tag"
+)
+
+// giteaDeleteEvent is delivered when a branch or tag is deleted.
+type giteaDeleteEvent struct {
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+
+	Repository struct {
+		Id       int    `json:"id"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type giteaTaskGenerator struct {
+	userAgent string
+}
+
+func newGiteaTaskGenerator(cfg *TopicConfig) taskGenerator {
+	return &giteaTaskGenerator{
+		userAgent: cfg.UserAgent,
+	}
+}
+
+func (d *giteaTaskGenerator) accept(header map[string]string) bool {
+	return header[headerGiteaEvent] != ""
+}
+
+func (d *giteaTaskGenerator) eventId(payload []byte, header map[string]string) string {
+	return header[headerGiteaDelivery]
+}
+
+func (d *giteaTaskGenerator) genTask(payload []byte, header map[string]string) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	eventType, err := d.parseRequest(header)
+	if err != nil {
+		err = fmt.Errorf("invalid task, err:%s", err.Error())
+
+		return
+	}
+
+	switch eventType {
+	case giteaEventPush:
+		e := new(pushEvent)
+		if err = json.Unmarshal(payload, e); err != nil {
+			return
+		}
+
+		cmd, ok, err = e.genTask()
+
+	case giteaEventDelete:
+		cmd, ok, err = d.genDeleteTask(payload)
+	}
+
+	cmd.Platform = platform.Gitea
+
+	return
+}
+
+// genDeleteTask handles the deletion of tag, since gitea doesn't
+// deliver the push event for it.
+func (d *giteaTaskGenerator) genDeleteTask(payload []byte) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	e := new(giteaDeleteEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	if e.RefType != giteaRefTypeTag {
+		return
+	}
+
+	if err = cmd.setRepo(e.Repository.FullName, e.Repository.Id); err != nil {
+		return
+	}
+
+	cmd.tag = strings.TrimPrefix(e.Ref, tagRefPrefix)
+	cmd.kind = taskKindDeleteTag
+	ok = true
+
+	return
+}
+
+func (d *giteaTaskGenerator) parseRequest(header map[string]string) (
+	eventType string, err error,
+) {
+	if header == nil {
+		err = errors.New("no header")
+
+		return
+	}
+
+	if !strings.HasPrefix(header["User-Agent"], d.userAgent) {
+		err = errors.New("unknown User-Agent")
+
+		return
+	}
+
+	if eventType = header[headerGiteaEvent]; eventType == "" {
+		err = errors.New("missing " + headerGiteaEvent)
+
+		return
+	}
+
+	if header[headerGiteaDelivery] == "" {
+		err = errors.New("missing " + headerGiteaDelivery)
+	}
+
+	return
+}
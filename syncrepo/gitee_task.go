@@ -0,0 +1,85 @@
+package syncrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+const (
+	headerGiteeEvent     = "X-Gitee-Event"
+	headerGiteeTimestamp = "X-Gitee-Timestamp"
+
+	giteeEventPush    = "Push Hook"
+	giteeEventTagPush = "Tag Push Hook"
+)
+
+type giteeTaskGenerator struct {
+	userAgent string
+}
+
+func newGiteeTaskGenerator(cfg *TopicConfig) taskGenerator {
+	return &giteeTaskGenerator{
+		userAgent: cfg.UserAgent,
+	}
+}
+
+func (d *giteeTaskGenerator) accept(header map[string]string) bool {
+	return header[headerGiteeEvent] != ""
+}
+
+// eventId is made of the timestamp and the digest of event, since gitee
+// doesn't deliver a unique id of event and the timestamp may be the same.
+func (d *giteeTaskGenerator) eventId(payload []byte, header map[string]string) string {
+	return header[headerGiteeTimestamp] + "-" + utils.GenMD5(payload)
+}
+
+func (d *giteeTaskGenerator) genTask(payload []byte, header map[string]string) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	eventType, err := d.parseRequest(header)
+	if err != nil {
+		err = fmt.Errorf("invalid task, err:%s", err.Error())
+
+		return
+	}
+
+	if eventType != giteeEventPush && eventType != giteeEventTagPush {
+		return
+	}
+
+	e := new(pushEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	cmd, ok, err = e.genTask()
+	cmd.Platform = platform.Gitee
+
+	return
+}
+
+func (d *giteeTaskGenerator) parseRequest(header map[string]string) (
+	eventType string, err error,
+) {
+	if header == nil {
+		err = errors.New("no header")
+
+		return
+	}
+
+	if header["User-Agent"] != d.userAgent {
+		err = errors.New("unknown User-Agent")
+
+		return
+	}
+
+	if eventType = header[headerGiteeEvent]; eventType == "" {
+		err = errors.New("missing " + headerGiteeEvent)
+	}
+
+	return
+}
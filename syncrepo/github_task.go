@@ -0,0 +1,91 @@
+package syncrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const (
+	headerGithubEvent    = "X-GitHub-Event"
+	headerGithubDelivery = "X-GitHub-Delivery"
+
+	githubEventPush = "push"
+)
+
+type githubTaskGenerator struct {
+	userAgent string
+}
+
+func newGithubTaskGenerator(cfg *TopicConfig) taskGenerator {
+	return &githubTaskGenerator{
+		userAgent: cfg.UserAgent,
+	}
+}
+
+func (d *githubTaskGenerator) accept(header map[string]string) bool {
+	return header[headerGithubEvent] != ""
+}
+
+func (d *githubTaskGenerator) eventId(payload []byte, header map[string]string) string {
+	return header[headerGithubDelivery]
+}
+
+// genTask handles the push event only, since both the pushes of
+// branches and tags are delivered by it.
+func (d *githubTaskGenerator) genTask(payload []byte, header map[string]string) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	eventType, err := d.parseRequest(header)
+	if err != nil {
+		err = fmt.Errorf("invalid task, err:%s", err.Error())
+
+		return
+	}
+
+	if eventType != githubEventPush {
+		return
+	}
+
+	e := new(pushEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	cmd, ok, err = e.genTask()
+	cmd.Platform = platform.Github
+
+	return
+}
+
+func (d *githubTaskGenerator) parseRequest(header map[string]string) (
+	eventType string, err error,
+) {
+	if header == nil {
+		err = errors.New("no header")
+
+		return
+	}
+
+	// the User-Agent of GitHub is like GitHub-Hookshot/044aadd
+	if !strings.HasPrefix(header["User-Agent"], d.userAgent) {
+		err = errors.New("unknown User-Agent")
+
+		return
+	}
+
+	if eventType = header[headerGithubEvent]; eventType == "" {
+		err = errors.New("missing " + headerGithubEvent)
+
+		return
+	}
+
+	if header[headerGithubDelivery] == "" {
+		err = errors.New("missing " + headerGithubDelivery)
+	}
+
+	return
+}
@@ -0,0 +1,196 @@
+package syncrepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const (
+	headerGitlabEvent = "X-Gitlab-Event"
+	headerEventUUID   = "X-Gitlab-Event-UUID"
+
+	systemEventProjectDestroy  = "project_destroy"
+	systemEventProjectRename   = "project_rename"
+	systemEventProjectTransfer = "project_transfer"
+)
+
+type gitlabTaskGenerator struct {
+	userAgent string
+}
+
+func newGitlabTaskGenerator(cfg *TopicConfig) taskGenerator {
+	return &gitlabTaskGenerator{
+		userAgent: cfg.UserAgent,
+	}
+}
+
+func (d *gitlabTaskGenerator) accept(header map[string]string) bool {
+	return header[headerGitlabEvent] != ""
+}
+
+func (d *gitlabTaskGenerator) eventId(payload []byte, header map[string]string) string {
+	return header[headerEventUUID]
+}
+
+func (d *gitlabTaskGenerator) genTask(payload []byte, header map[string]string) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	eventType, err := d.parseRequest(payload, header)
+	if err != nil {
+		err = fmt.Errorf("invalid task, err:%s", err.Error())
+
+		return
+	}
+
+	switch gitlab.EventType(eventType) {
+	case gitlab.EventTypePush:
+		cmd, ok, err = d.genPushTask(payload)
+
+	case gitlab.EventTypeTagPush:
+		cmd, ok, err = d.genTagTask(payload)
+
+	case gitlab.EventTypeSystemHook:
+		cmd, ok, err = d.genSystemTask(payload)
+	}
+
+	cmd.Platform = platform.Gitlab
+
+	return
+}
+
+func (d *gitlabTaskGenerator) genPushTask(payload []byte) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	e := new(gitlab.PushEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	// there is nothing to sync when the branch is deleted.
+	if isZeroCommit(e.After) {
+		return
+	}
+
+	if cmd.Branch = strings.TrimPrefix(e.Ref, branchRefPrefix); cmd.Branch == e.Ref {
+		err = fmt.Errorf("invalid ref:%s", e.Ref)
+
+		return
+	}
+
+	if err = cmd.setRepo(e.Project.PathWithNamespace, e.ProjectID); err != nil {
+		return
+	}
+
+	cmd.DefaultBranch = e.Project.DefaultBranch
+	cmd.kind = taskKindSync
+	ok = true
+
+	return
+}
+
+func (d *gitlabTaskGenerator) genTagTask(payload []byte) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	e := new(gitlab.TagEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	if cmd.tag = strings.TrimPrefix(e.Ref, tagRefPrefix); cmd.tag == e.Ref {
+		err = fmt.Errorf("invalid ref:%s", e.Ref)
+
+		return
+	}
+
+	if err = cmd.setRepo(e.Project.PathWithNamespace, e.ProjectID); err != nil {
+		return
+	}
+
+	if isZeroCommit(e.After) {
+		cmd.kind = taskKindDeleteTag
+	} else {
+		cmd.kind = taskKindTag
+	}
+
+	ok = true
+
+	return
+}
+
+// genSystemTask handles the system hook events of projects.
+func (d *gitlabTaskGenerator) genSystemTask(payload []byte) (
+	cmd syncRepoTask, ok bool, err error,
+) {
+	e := new(gitlab.ProjectSystemEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		return
+	}
+
+	switch e.EventName {
+	case systemEventProjectDestroy:
+		cmd.kind = taskKindDestroy
+
+	case systemEventProjectRename, systemEventProjectTransfer:
+		cmd.kind = taskKindMove
+
+	default:
+		return
+	}
+
+	if err = cmd.setRepo(e.PathWithNamespace, e.ProjectID); err != nil {
+		return
+	}
+
+	if cmd.kind == taskKindMove {
+		old := syncRepoTask{}
+		if err = old.setRepo(e.OldPathWithNamespace, e.ProjectID); err != nil {
+			return
+		}
+
+		// the obs path of repo doesn't include the repo name,
+		// so there is nothing to do if the owner is not changed.
+		if old.Owner.Account() == cmd.Owner.Account() {
+			return
+		}
+
+		cmd.from = old.Owner
+	}
+
+	ok = true
+
+	return
+}
+
+func (d *gitlabTaskGenerator) parseRequest(payload []byte, header map[string]string) (
+	eventType string, err error,
+) {
+	if header == nil {
+		err = errors.New("no header")
+
+		return
+	}
+
+	if header["User-Agent"] != d.userAgent {
+		err = errors.New("unknown User-Agent")
+
+		return
+	}
+
+	if eventType = header[headerGitlabEvent]; eventType == "" {
+		err = errors.New("missing " + headerGitlabEvent)
+
+		return
+	}
+
+	if header[headerEventUUID] == "" {
+		err = errors.New("missing " + headerEventUUID)
+	}
+
+	return
+}
@@ -0,0 +1,183 @@
+package syncrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/election"
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const reconcilerLeaderName = "xihe-sync-repo-reconciler"
+
+// Reconciler compares all the repos of platform with the sync state
+// periodically and syncs the stale ones, in case the push events are lost.
+// Only the leader of instances does it.
+type Reconciler struct {
+	cfg     ReconcileConfig
+	holder  string
+	repo    *SyncRepo
+	service app.SyncService
+	lister  platform.RepoLister
+	elector election.Elector
+	limiter *rate.Limiter
+
+	owners         map[string]bool
+	excludedOwners map[string]bool
+}
+
+func NewReconciler(
+	cfg *ReconcileConfig, holder string, repo *SyncRepo,
+	service app.SyncService, lister platform.RepoLister, elector election.Elector,
+) *Reconciler {
+	return &Reconciler{
+		cfg:     *cfg,
+		holder:  holder,
+		repo:    repo,
+		service: service,
+		lister:  lister,
+		elector: elector,
+		limiter: rate.NewLimiter(rate.Limit(cfg.RateLimit), 1),
+
+		owners:         toSet(cfg.Owners),
+		excludedOwners: toSet(cfg.ExcludedOwners),
+	}
+}
+
+func (r *Reconciler) Run(ctx context.Context, log *logrus.Entry) {
+	if r.cfg.Interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(time.Duration(r.cfg.Interval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-t.C:
+			if r.isLeader(log) {
+				r.reconcile(ctx, log)
+			}
+		}
+	}
+}
+
+// isLeader acquires or renews the leadership. The lease outlives the
+// interval, so that the leader keeps it across the rounds.
+func (r *Reconciler) isLeader(log *logrus.Entry) bool {
+	ok, err := r.elector.TryAcquire(
+		reconcilerLeaderName, r.holder, int64(2*r.cfg.Interval),
+	)
+	if err != nil {
+		log.Errorf("elect reconciler leader failed, err:%s", err.Error())
+	}
+
+	return ok
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, log *logrus.Entry) {
+	n := 0
+
+	for page := 1; ; page++ {
+		repos, more, err := r.lister.ListRepos(page)
+		if err != nil {
+			log.Errorf("list repos of page %d failed, err:%s", page, err.Error())
+
+			return
+		}
+
+		for i := range repos {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if r.reconcileRepo(ctx, &repos[i], log) {
+				n++
+			}
+		}
+
+		if !more {
+			break
+		}
+
+		// renew the lease and stop if it is lost.
+		if !r.isLeader(log) {
+			return
+		}
+	}
+
+	log.Infof("reconcile done, %d stale repos are found", n)
+}
+
+func (r *Reconciler) reconcileRepo(
+	ctx context.Context, repo *platform.Repo, log *logrus.Entry,
+) bool {
+	if !r.isWatched(repo.Owner) {
+		return false
+	}
+
+	owner, err := domain.NewAccount(repo.Owner)
+	if err != nil {
+		return false
+	}
+
+	info := app.RepoInfo{
+		Owner:    owner,
+		RepoId:   repo.Id,
+		RepoName: repo.Name,
+		Platform: platform.Gitlab,
+	}
+
+	if err := r.limiter.Wait(ctx); err != nil {
+		return false
+	}
+
+	stale, err := r.service.IsStale(&info)
+	if err != nil {
+		log.Errorf(
+			"check repo(%s/%s) failed, err:%s", repo.Owner, repo.Name, err.Error(),
+		)
+
+		return false
+	}
+
+	if !stale {
+		return false
+	}
+
+	log.Infof("repo(%s/%s) is stale, sync it", repo.Owner, repo.Name)
+
+	if err := r.repo.dispatchWait(ctx, &info); err != nil {
+		log.Errorf(
+			"dispatch repo(%s/%s) failed, err:%s",
+			repo.Owner, repo.Name, err.Error(),
+		)
+	}
+
+	return true
+}
+
+func (r *Reconciler) isWatched(owner string) bool {
+	if r.excludedOwners[owner] {
+		return false
+	}
+
+	return len(r.owners) == 0 || r.owners[owner]
+}
+
+func toSet(v []string) map[string]bool {
+	r := make(map[string]bool, len(v))
+	for _, item := range v {
+		r[item] = true
+	}
+
+	return r
+}
@@ -0,0 +1,244 @@
+package syncrepo
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+)
+
+var errorQueueFull = errors.New("too many tasks, try again later")
+
+// repoQueue dispatches the tasks to the worker pool through ch, and
+// serializes the tasks of the same repo within the pool.
+//
+// The worker which gets a task of the repo being handled by another worker
+// puts it to the pending queue of repo and goes on with the next task, and
+// the pending tasks are handled in order by the worker handling the repo.
+// So the idle workers are never blocked by a busy repo.
+//
+// The bursts of sync tasks for a repo collapse into the sync task which
+// is queued but not started yet, so they don't take the slots of ch.
+//
+// The tasks of a repo which are not started are limited by repoSize, so
+// a busy repo can't move its tasks out of ch without limit.
+type repoQueue struct {
+	ch       chan *message
+	repoSize int
+
+	lock sync.Mutex
+	// cond is signaled when a task is taken from ch or started.
+	cond  *sync.Cond
+	repos map[string]*repoTasks
+}
+
+// repoTasks are the tasks of a repo which are queued or running.
+type repoTasks struct {
+	// queued is the number of tasks which are not started.
+	queued  int
+	running bool
+
+	// pending are the tasks waiting for the running one, in the order
+	// they arrive.
+	pending []*message
+
+	// last is the last task which is not started. Only it can absorb
+	// the newer sync tasks, so that a sync will not be handled before
+	// the other tasks which arrive earlier.
+	last *message
+}
+
+func newRepoQueue(size, repoSize int) *repoQueue {
+	q := &repoQueue{
+		ch:       make(chan *message, size),
+		repoSize: repoSize,
+		repos:    map[string]*repoTasks{},
+	}
+	q.cond = sync.NewCond(&q.lock)
+
+	return q
+}
+
+// canCoalesce checks whether the message can be collapsed. The retried
+// message is not, because its record must be done by the worker.
+func (q *repoQueue) canCoalesce(msg *message) bool {
+	return msg.task.kind == taskKindSync && msg.retry == nil
+}
+
+// add sends the message to ch unless it is collapsed into a queued one.
+// It waits until both ch and the repo have room or ctx is done.
+func (q *repoQueue) add(ctx context.Context, msg *message) error {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// wake up the waiting below.
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.lock.Lock()
+			q.cond.Broadcast()
+			q.lock.Unlock()
+
+		case <-stop:
+		}
+	}()
+
+	return q.dispatch(msg, ctx.Err)
+}
+
+// tryAdd is the same as add except that it returns errorQueueFull
+// instead of waiting.
+func (q *repoQueue) tryAdd(msg *message) error {
+	return q.dispatch(msg, func() error {
+		return errorQueueFull
+	})
+}
+
+// dispatch calls wait before waiting for the room, and stops if it fails.
+func (q *repoQueue) dispatch(msg *message, wait func() error) error {
+	key := msg.task.repoKey()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for {
+		t := q.repos[key]
+
+		if t != nil && t.last != nil && q.canCoalesce(t.last) && q.canCoalesce(msg) {
+			q.coalesce(t.last, msg)
+
+			metrics.IncCoalescedTask()
+
+			return nil
+		}
+
+		if t == nil || t.queued < q.repoSize {
+			select {
+			case q.ch <- msg:
+				if t == nil {
+					t = new(repoTasks)
+					q.repos[key] = t
+				}
+
+				t.queued++
+				t.last = msg
+
+				return nil
+
+			default:
+			}
+		}
+
+		if err := wait(); err != nil {
+			return err
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// coalesce collapses msg into the queued one. The newer event is kept to
+// be retried if the sync fails, and the older one is recorded as handled
+// together with it.
+func (q *repoQueue) coalesce(queued, msg *message) {
+	queued.task = msg.task
+
+	// the pushed branch may be not the tracked one, so sync the
+	// tracked branch to the latest which includes all the pushes.
+	queued.task.Branch = ""
+
+	// the task triggered manually has no event.
+	if msg.msg == nil {
+		return
+	}
+
+	if queued.eventId != "" {
+		queued.collapsed = append(queued.collapsed, queued.eventId)
+	}
+
+	queued.msg, queued.eventId, queued.topic = msg.msg, msg.eventId, msg.topic
+}
+
+// close closes ch after which add must not be called.
+func (q *repoQueue) close() {
+	close(q.ch)
+}
+
+// start is called after the message is taken from ch. It returns true
+// if the message should be handled now. Otherwise, it has been put to
+// the pending queue of repo.
+func (q *repoQueue) start(msg *message) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.cond.Broadcast()
+
+	t := q.repos[msg.task.repoKey()]
+	if t.running {
+		t.pending = append(t.pending, msg)
+
+		return false
+	}
+
+	t.running = true
+	q.begin(t, msg)
+
+	return true
+}
+
+// done is called after the message is handled. It returns the next
+// pending task of repo which should be handled right after if there is.
+func (q *repoQueue) done(msg *message) (*message, bool) {
+	key := msg.task.repoKey()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	t := q.repos[key]
+	if len(t.pending) == 0 {
+		t.running = false
+
+		if t.queued == 0 {
+			delete(q.repos, key)
+		}
+
+		return nil, false
+	}
+
+	next := t.pending[0]
+	t.pending = t.pending[1:]
+	q.begin(t, next)
+
+	return next, true
+}
+
+// begin marks the message as started, so that it can't absorb the others.
+func (q *repoQueue) begin(t *repoTasks, msg *message) {
+	q.cond.Broadcast()
+
+	t.queued--
+
+	if t.last == msg {
+		t.last = nil
+	}
+}
+
+// occupancy returns the number of tasks waiting in ch.
+func (q *repoQueue) occupancy() int {
+	return len(q.ch)
+}
+
+// pendings returns the number of tasks waiting for the running task of
+// the same repo.
+func (q *repoQueue) pendings() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	n := 0
+	for _, t := range q.repos {
+		n += len(t.pending)
+	}
+
+	return n
+}
@@ -0,0 +1,289 @@
+package syncrepo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/opensourceways/community-robot-lib/mq"
+
+	"github.com/opensourceways/xihe-sync-repo/domain"
+)
+
+func newTestMessage(t *testing.T, repoId, kind, eventId string) *message {
+	owner, err := domain.NewAccount("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &message{
+		task: syncRepoTask{kind: kind},
+	}
+	msg.task.Owner = owner
+	msg.task.RepoId = repoId
+	msg.task.Branch = "main"
+
+	// the task triggered manually has no event.
+	if eventId != "" {
+		msg.msg = &mq.Message{Body: []byte(eventId)}
+		msg.eventId = eventId
+	}
+
+	return msg
+}
+
+// queuedEvents returns the event and the collapsed ones of each queued message.
+func queuedEvents(q *repoQueue) [][]string {
+	r := [][]string{}
+
+	for n := len(q.ch); n > 0; n-- {
+		msg := <-q.ch
+		q.ch <- msg
+
+		r = append(r, append([]string{msg.eventId}, msg.collapsed...))
+	}
+
+	return r
+}
+
+func TestRepoQueueCoalesce(t *testing.T) {
+	type item struct {
+		repoId  string
+		kind    string
+		eventId string
+		retry   bool
+	}
+
+	cases := []struct {
+		name  string
+		items []item
+		// want are the event of each queued message and the ones collapsed into it.
+		want [][]string
+	}{
+		{
+			name: "syncs collapse into the queued one",
+			items: []item{
+				{"1", taskKindSync, "e1", false},
+				{"1", taskKindSync, "e2", false},
+				{"1", taskKindSync, "e3", false},
+			},
+			want: [][]string{{"e3", "e1", "e2"}},
+		},
+		{
+			name: "manual sync takes the event",
+			items: []item{
+				{"1", taskKindSync, "", false},
+				{"1", taskKindSync, "e1", false},
+			},
+			want: [][]string{{"e1"}},
+		},
+		{
+			name: "manual sync keeps the event",
+			items: []item{
+				{"1", taskKindSync, "e1", false},
+				{"1", taskKindSync, "", false},
+			},
+			want: [][]string{{"e1"}},
+		},
+		{
+			name: "sync does not jump over the other tasks",
+			items: []item{
+				{"1", taskKindSync, "e1", false},
+				{"1", taskKindTag, "e2", false},
+				{"1", taskKindSync, "e3", false},
+				{"1", taskKindSync, "e4", false},
+			},
+			want: [][]string{{"e1"}, {"e2"}, {"e4", "e3"}},
+		},
+		{
+			name: "retry is not collapsed",
+			items: []item{
+				{"1", taskKindSync, "e1", true},
+				{"1", taskKindSync, "e2", false},
+				{"1", taskKindSync, "e3", true},
+			},
+			want: [][]string{{"e1"}, {"e2"}, {"e3"}},
+		},
+		{
+			name: "repos are not collapsed",
+			items: []item{
+				{"1", taskKindSync, "e1", false},
+				{"2", taskKindSync, "e2", false},
+				{"1", taskKindSync, "e3", false},
+			},
+			want: [][]string{{"e3", "e1"}, {"e2"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := newRepoQueue(len(c.items), len(c.items))
+
+			for _, v := range c.items {
+				msg := newTestMessage(t, v.repoId, v.kind, v.eventId)
+				if v.retry {
+					msg.retry = &domain.RetryTask{EventId: v.eventId}
+				}
+
+				if err := q.tryAdd(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if v := queuedEvents(q); !reflect.DeepEqual(v, c.want) {
+				t.Errorf("got %v, want %v", v, c.want)
+			}
+		})
+	}
+}
+
+func TestRepoQueueCoalesceBranch(t *testing.T) {
+	q := newRepoQueue(1, 10)
+
+	for _, id := range []string{"e1", "e2"} {
+		if err := q.tryAdd(newTestMessage(t, "1", taskKindSync, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the pushes may be to different branches.
+	if msg := <-q.ch; msg.task.Branch != "" {
+		t.Errorf("got branch %s, want the tracked one", msg.task.Branch)
+	}
+}
+
+func TestRepoQueueNotCoalesceStarted(t *testing.T) {
+	q := newRepoQueue(2, 10)
+
+	if err := q.add(context.Background(), newTestMessage(t, "1", taskKindSync, "e1")); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-q.ch
+	if !q.start(msg) {
+		t.Fatal("the task should be started")
+	}
+
+	// the running sync may miss the new commits, so sync again.
+	if err := q.add(context.Background(), newTestMessage(t, "1", taskKindSync, "e2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := queuedEvents(q); !reflect.DeepEqual(v, [][]string{{"e2"}}) {
+		t.Errorf("got %v, want e2 is queued", v)
+	}
+
+	if len(msg.collapsed) != 0 || msg.eventId != "e1" {
+		t.Errorf("the started task is changed: %s %v", msg.eventId, msg.collapsed)
+	}
+}
+
+func TestRepoQueueFull(t *testing.T) {
+	q := newRepoQueue(1, 10)
+
+	if err := q.tryAdd(newTestMessage(t, "1", taskKindSync, "e1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the sync of the same repo is collapsed even if the queue is full.
+	if err := q.tryAdd(newTestMessage(t, "1", taskKindSync, "e2")); err != nil {
+		t.Errorf("got %v, want it is collapsed", err)
+	}
+
+	if err := q.tryAdd(newTestMessage(t, "1", taskKindTag, "e3")); err != errorQueueFull {
+		t.Errorf("got %v, want errorQueueFull", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := q.add(ctx, newTestMessage(t, "2", taskKindSync, "e4")); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestRepoQueueOrder(t *testing.T) {
+	q := newRepoQueue(4, 10)
+
+	items := []struct {
+		repoId  string
+		eventId string
+	}{
+		{"1", "e1"},
+		{"1", "e2"},
+		{"2", "e3"},
+		{"1", "e4"},
+	}
+
+	for _, v := range items {
+		if err := q.tryAdd(newTestMessage(t, v.repoId, taskKindTag, v.eventId)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first worker takes e1 of repo 1.
+	first := <-q.ch
+	if !q.start(first) {
+		t.Fatal("e1 should be started")
+	}
+
+	// the tasks of repo 1 wait for e1, but the one of repo 2 does not.
+	started := []string{}
+	for n := len(q.ch); n > 0; n-- {
+		if msg := <-q.ch; q.start(msg) {
+			started = append(started, msg.eventId)
+		}
+	}
+
+	if !reflect.DeepEqual(started, []string{"e3"}) {
+		t.Errorf("got started %v, want [e3]", started)
+	}
+
+	// the pending tasks of repo 1 are handled in order.
+	done := []string{}
+	for msg, ok := first, true; ok; msg, ok = q.done(msg) {
+		done = append(done, msg.eventId)
+	}
+
+	if want := []string{"e1", "e2", "e4"}; !reflect.DeepEqual(done, want) {
+		t.Errorf("got %v, want %v", done, want)
+	}
+
+	if _, ok := q.repos[first.task.repoKey()]; ok {
+		t.Error("the state of repo 1 is not cleaned up")
+	}
+}
+
+func TestRepoQueueRepoFull(t *testing.T) {
+	q := newRepoQueue(4, 2)
+
+	for _, id := range []string{"e1", "e2"} {
+		if err := q.tryAdd(newTestMessage(t, "1", taskKindTag, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the repo is full even if ch has room.
+	if err := q.tryAdd(newTestMessage(t, "1", taskKindTag, "e3")); err != errorQueueFull {
+		t.Errorf("got %v, want errorQueueFull", err)
+	}
+
+	// the other repo is not affected.
+	if err := q.tryAdd(newTestMessage(t, "2", taskKindTag, "e4")); err != nil {
+		t.Errorf("got %v, want the task of repo 2 is queued", err)
+	}
+
+	// the waiting task is queued after a task of the repo is started.
+	errs := make(chan error)
+	go func() {
+		errs <- q.add(context.Background(), newTestMessage(t, "1", taskKindTag, "e5"))
+	}()
+
+	if msg := <-q.ch; !q.start(msg) {
+		t.Fatal("e1 should be started")
+	}
+
+	if err := <-errs; err != nil {
+		t.Errorf("got %v, want e5 is queued", err)
+	}
+}
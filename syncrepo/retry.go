@@ -0,0 +1,157 @@
+package syncrepo
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+	"github.com/opensourceways/xihe-sync-repo/utils"
+)
+
+type retryer struct {
+	cfg  RetryConfig
+	repo retrytask.RetryTaskRepo
+
+	// jitter is not safe for concurrent use, so it is protected by jitterLock.
+	jitter     *rand.Rand
+	jitterLock sync.Mutex
+}
+
+func newRetryer(cfg *RetryConfig, repo retrytask.RetryTaskRepo) *retryer {
+	return &retryer{
+		cfg:    *cfg,
+		repo:   repo,
+		jitter: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// backoff returns the exponential delay with jitter for the n-th retry.
+func (r *retryer) backoff(n int) time.Duration {
+	max := time.Duration(r.cfg.MaxDelay) * time.Second
+
+	d := time.Duration(r.cfg.BaseDelay) * time.Second
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	// pick a random delay between [d/2, d]
+	half := d / 2
+
+	r.jitterLock.Lock()
+	v := r.jitter.Int63n(int64(half) + 1)
+	r.jitterLock.Unlock()
+
+	return half + time.Duration(v)
+}
+
+// fail records the failure of message. The message will be quarantined
+// if it has run out of attempts. The failure because the repo is being
+// synced by others is not counted as an attempt, since it is not a failure
+// of the event itself.
+func (r *retryer) fail(msg *message, reason error) (domain.RetryTask, error) {
+	var t domain.RetryTask
+
+	if msg.retry != nil {
+		t = *msg.retry
+	} else {
+		t.EventId = msg.eventId
+		if t.EventId == "" {
+			// keep the retries of events without id distinguishable.
+			t.EventId = uuid.NewString()
+		}
+		t.Topic = msg.topic
+		t.Header = msg.msg.Header
+		t.Payload = msg.msg.Body
+	}
+
+	t.LastError = reason.Error()
+
+	if app.IsErrorRepoBusy(reason) {
+		t.NextRetryAt = time.Now().Add(r.backoff(t.Attempts + 1)).Unix()
+	} else {
+		t.Attempts++
+
+		// the repo too large will fail again until the workspace is expanded.
+		if t.Attempts >= r.cfg.MaxAttempts || app.IsErrorRepoTooLarge(reason) {
+			t.Quarantined = true
+		} else {
+			t.NextRetryAt = time.Now().Add(r.backoff(t.Attempts)).Unix()
+		}
+	}
+
+	err := utils.Retry(func() error {
+		_, err := r.repo.Save(&t)
+
+		return err
+	})
+
+	return t, err
+}
+
+func (r *retryer) done(t *domain.RetryTask) error {
+	return utils.Retry(func() error {
+		return r.repo.Delete(t.Id)
+	})
+}
+
+// claim reserves the due tasks, so that the other instances will not
+// handle them at the same time.
+func (r *retryer) claim(limit int) ([]domain.RetryTask, error) {
+	now := time.Now()
+
+	tasks, err := r.repo.FindDue(now.Unix(), limit)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	next := now.Add(time.Duration(r.cfg.ClaimTimeout) * time.Second).Unix()
+
+	claimed := make([]domain.RetryTask, 0, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		t.NextRetryAt = next
+
+		v, err := r.repo.Save(t)
+		if err != nil {
+			if retrytask.IsErrorConcurrentUpdating(err) {
+				continue
+			}
+
+			return claimed, err
+		}
+
+		claimed = append(claimed, v)
+	}
+
+	return claimed, nil
+}
+
+func (r *retryer) quarantined() ([]domain.RetryTask, error) {
+	return r.repo.FindQuarantined()
+}
+
+// replay puts the quarantined task back to the retry queue with
+// a fresh attempt count.
+func (r *retryer) replay(id string) error {
+	t, err := r.repo.Find(id)
+	if err != nil {
+		return err
+	}
+
+	t.Attempts = 0
+	t.Quarantined = false
+	t.NextRetryAt = time.Now().Unix()
+
+	_, err = r.repo.Save(&t)
+
+	return err
+}
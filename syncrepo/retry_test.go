@@ -0,0 +1,202 @@
+package syncrepo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opensourceways/community-robot-lib/mq"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+)
+
+// fakeRetryTaskRepo saves the tasks in memory.
+type fakeRetryTaskRepo struct {
+	tasks map[string]domain.RetryTask
+}
+
+func newFakeRetryTaskRepo() *fakeRetryTaskRepo {
+	return &fakeRetryTaskRepo{tasks: map[string]domain.RetryTask{}}
+}
+
+func (r *fakeRetryTaskRepo) Save(t *domain.RetryTask) (domain.RetryTask, error) {
+	if t.Id == "" {
+		t.Id = t.EventId
+	}
+
+	r.tasks[t.Id] = *t
+
+	return *t, nil
+}
+
+func (r *fakeRetryTaskRepo) Find(id string) (domain.RetryTask, error) {
+	t, ok := r.tasks[id]
+	if !ok {
+		return t, retrytask.NewErrorTaskNotExists(errors.New("not exists"))
+	}
+
+	return t, nil
+}
+
+func (r *fakeRetryTaskRepo) FindDue(now int64, limit int) ([]domain.RetryTask, error) {
+	var v []domain.RetryTask
+
+	for _, t := range r.tasks {
+		if !t.Quarantined && t.NextRetryAt <= now && len(v) < limit {
+			v = append(v, t)
+		}
+	}
+
+	return v, nil
+}
+
+func (r *fakeRetryTaskRepo) FindQuarantined() ([]domain.RetryTask, error) {
+	var v []domain.RetryTask
+
+	for _, t := range r.tasks {
+		if t.Quarantined {
+			v = append(v, t)
+		}
+	}
+
+	return v, nil
+}
+
+func (r *fakeRetryTaskRepo) Delete(id string) error {
+	delete(r.tasks, id)
+
+	return nil
+}
+
+func TestRetryerBackoff(t *testing.T) {
+	r := newRetryer(&RetryConfig{BaseDelay: 10, MaxDelay: 60}, newFakeRetryTaskRepo())
+
+	cases := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 60 * time.Second},
+		{100, 60 * time.Second},
+	}
+
+	for _, c := range cases {
+		// the delay is random, so check it several times.
+		for i := 0; i < 100; i++ {
+			if v := r.backoff(c.n); v < c.want/2 || v > c.want {
+				t.Fatalf("the %d-th retry: got %s, want in [%s, %s]", c.n, v, c.want/2, c.want)
+			}
+		}
+	}
+}
+
+func TestRetryerFail(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		err      error
+		// wantAttempts is the attempts after the failure.
+		wantAttempts    int
+		wantQuarantined bool
+	}{
+		{
+			name:         "first failure",
+			err:          errors.New("failed"),
+			wantAttempts: 1,
+		},
+		{
+			name:         "busy is not an attempt",
+			attempts:     2,
+			err:          app.NewErrorRepoBusy(errors.New("busy")),
+			wantAttempts: 2,
+		},
+		{
+			name:            "run out of attempts",
+			attempts:        2,
+			err:             errors.New("failed"),
+			wantAttempts:    3,
+			wantQuarantined: true,
+		},
+		{
+			name:            "repo too large",
+			err:             app.NewErrorRepoTooLarge(errors.New("too large")),
+			wantAttempts:    1,
+			wantQuarantined: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := newFakeRetryTaskRepo()
+			r := newRetryer(&RetryConfig{MaxAttempts: 3, BaseDelay: 10, MaxDelay: 60}, repo)
+
+			msg := &message{
+				msg:     &mq.Message{Body: []byte("e1")},
+				eventId: "e1",
+				topic:   "topic",
+			}
+			if c.attempts > 0 {
+				msg.retry = &domain.RetryTask{
+					Id: "e1", EventId: "e1", Topic: "topic", Attempts: c.attempts,
+				}
+			}
+
+			now := time.Now().Unix()
+
+			v, err := r.fail(msg, c.err)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Attempts != c.wantAttempts || v.Quarantined != c.wantQuarantined {
+				t.Errorf(
+					"got attempts %d quarantined %t, want %d %t",
+					v.Attempts, v.Quarantined, c.wantAttempts, c.wantQuarantined,
+				)
+			}
+
+			if !v.Quarantined && v.NextRetryAt < now+5 {
+				t.Errorf("got next retry at %d, want it is delayed", v.NextRetryAt)
+			}
+
+			if v.LastError != c.err.Error() || v.Topic != "topic" {
+				t.Errorf("got %+v", v)
+			}
+
+			if _, err := repo.Find(v.Id); err != nil {
+				t.Errorf("the task is not saved, err:%v", err)
+			}
+		})
+	}
+}
+
+func TestRetryerReplay(t *testing.T) {
+	repo := newFakeRetryTaskRepo()
+	r := newRetryer(&RetryConfig{MaxAttempts: 1, BaseDelay: 10, MaxDelay: 60}, repo)
+
+	msg := &message{msg: &mq.Message{Body: []byte("e1")}, eventId: "e1"}
+	if _, err := r.fail(msg, errors.New("failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := r.quarantined(); len(v) != 1 {
+		t.Fatalf("got %d quarantined, want 1", len(v))
+	}
+
+	if err := r.replay("e1"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := r.claim(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v) != 1 || v[0].Attempts != 0 || v[0].Quarantined {
+		t.Errorf("got %+v, want the replayed task", v)
+	}
+}
@@ -1,68 +1,136 @@
 package syncrepo
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"sync"
+	"time"
 
 	"github.com/opensourceways/community-robot-lib/kafka"
 	"github.com/opensourceways/community-robot-lib/mq"
-	"github.com/opensourceways/community-robot-lib/utils"
 	"github.com/sirupsen/logrus"
 
 	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/dedup"
+	"github.com/opensourceways/xihe-sync-repo/domain/retrytask"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
 )
 
 type message struct {
 	msg  *mq.Message
 	task syncRepoTask
+
+	// eventId is the unique id of event which msg carries.
+	eventId string
+
+	// collapsed are the ids of events which are collapsed into this one.
+	collapsed []string
+
+	// topic is the topic which msg is received from.
+	topic string
+
+	// retry is not nil if the message is from the retry queue.
+	retry *domain.RetryTask
+}
+
+// subscription is a topic and the handlers of its events.
+type subscription struct {
+	topic     string
+	verifier  eventVerifier
+	generator taskGenerator
 }
 
 type SyncRepo struct {
-	hmac        string
-	topic       string
-	endpoint    string
-	hc          utils.HttpClient
-	generator   syncRepoTaskGenerator
-	syncservice app.SyncService
+	subscriptions []subscription
+	retryer       *retryer
+	dedup         dedup.EventDedup
+	repos         *repoQueue
+	syncservice   app.SyncService
+
+	pollInterval time.Duration
 
 	wg              sync.WaitGroup
-	messageChan     chan message
 	messageChanSize int
+
+	// closed is protected by closeLock. It is used to avoid
+	// dispatching task to the closed message channel.
+	closed    bool
+	closeLock sync.RWMutex
 }
 
-func NewSyncRepo(cfg *Config, service app.SyncService) *SyncRepo {
+func NewSyncRepo(
+	cfg *Config, service app.SyncService,
+	repo retrytask.RetryTaskRepo, eventDedup dedup.EventDedup,
+) *SyncRepo {
 	size := cfg.concurrentSize()
 
-	return &SyncRepo{
-		hmac:     cfg.AccessHmac,
-		topic:    cfg.Topic,
-		endpoint: cfg.AccessEndpoint,
+	topics := cfg.AllTopics()
+	subscriptions := make([]subscription, len(topics))
+	for i := range topics {
+		subscriptions[i] = subscription{
+			topic:     topics[i].Topic,
+			verifier:  newEventVerifier(&topics[i]),
+			generator: newTaskGenerator(&topics[i]),
+		}
+	}
+
+	d := &SyncRepo{
+		subscriptions: subscriptions,
+		retryer:       newRetryer(&cfg.Retry, repo),
+		dedup:         eventDedup,
+		repos:         newRepoQueue(size, cfg.RepoQueueSize),
+		syncservice:   service,
 
-		hc: utils.NewHttpClient(3),
-		generator: syncRepoTaskGenerator{
-			userAgent: cfg.UserAgent,
-		},
-		syncservice: service,
+		pollInterval: time.Duration(cfg.Retry.PollInterval) * time.Second,
 
-		messageChan:     make(chan message, size),
 		messageChanSize: size,
 	}
+
+	metrics.RegisterQueueOccupancy(func() float64 {
+		return float64(d.repos.occupancy())
+	})
+
+	metrics.RegisterPendingTasks(func() float64 {
+		return float64(d.repos.pendings())
+	})
+
+	return d
 }
 
 func (d *SyncRepo) Run(ctx context.Context, log *logrus.Entry) error {
-	s, err := kafka.Subscribe(
-		d.topic,
-		d.handle,
-		func(opt *mq.SubscribeOptions) {
-			opt.Queue = "xihe-sync-repo"
-		},
-	)
-	if err != nil {
-		return err
+	subscribers := make([]mq.Subscriber, 0, len(d.subscriptions))
+
+	unsubscribe := func() {
+		for _, s := range subscribers {
+			s.Unsubscribe()
+		}
+	}
+
+	for i := range d.subscriptions {
+		item := &d.subscriptions[i]
+
+		if item.verifier.cfg.Disabled {
+			log.Warnf("the verification of topic:%s is disabled", item.topic)
+		}
+
+		s, err := kafka.Subscribe(
+			item.topic,
+			func(event mq.Event) error {
+				return d.handle(event, item, log)
+			},
+			func(opt *mq.SubscribeOptions) {
+				opt.Queue = "xihe-sync-repo"
+			},
+		)
+		if err != nil {
+			unsubscribe()
+
+			return err
+		}
+
+		subscribers = append(subscribers, s)
 	}
 
 	for i := 0; i < d.messageChanSize; i++ {
@@ -74,35 +142,155 @@ func (d *SyncRepo) Run(ctx context.Context, log *logrus.Entry) error {
 		}()
 	}
 
+	retryDone := make(chan struct{})
+	go func() {
+		d.pollRetryTasks(ctx, log)
+		close(retryDone)
+	}()
+
 	<-ctx.Done()
 
-	s.Unsubscribe()
+	unsubscribe()
+
+	<-retryDone
 
-	close(d.messageChan)
+	d.closeLock.Lock()
+	d.closed = true
+	d.repos.close()
+	d.closeLock.Unlock()
 
 	d.wg.Wait()
 
 	return nil
 }
 
-func (d *SyncRepo) handle(event mq.Event) error {
+// Dispatch adds a sync task triggered manually to the worker pool.
+func (d *SyncRepo) Dispatch(task *app.RepoInfo) error {
+	d.closeLock.RLock()
+	defer d.closeLock.RUnlock()
+
+	if d.closed {
+		return errors.New("the service is stopped")
+	}
+
+	return d.repos.tryAdd(&message{task: newSyncTask(task)})
+}
+
+// dispatchWait adds the task to the worker pool and waits until
+// there is an idle slot.
+func (d *SyncRepo) dispatchWait(ctx context.Context, task *app.RepoInfo) error {
+	d.closeLock.RLock()
+	defer d.closeLock.RUnlock()
+
+	if d.closed {
+		return errors.New("the service is stopped")
+	}
+
+	return d.repos.add(ctx, &message{task: newSyncTask(task)})
+}
+
+// QuarantinedTasks returns the events which have run out of attempts.
+func (d *SyncRepo) QuarantinedTasks() ([]domain.RetryTask, error) {
+	return d.retryer.quarantined()
+}
+
+// ReplayTask puts the quarantined event back to the retry queue.
+func (d *SyncRepo) ReplayTask(id string) error {
+	return d.retryer.replay(id)
+}
+
+func (d *SyncRepo) handle(event mq.Event, sub *subscription, log *logrus.Entry) error {
 	msg := event.Message()
 
 	if err := d.validateMessage(msg); err != nil {
 		return err
 	}
 
-	task, ok, err := d.generator.genTask(msg.Body, msg.Header)
+	// the event to be retried has been verified, so verify it here only.
+	if err := sub.verifier.verify(msg.Body, msg.Header); err != nil {
+		return fmt.Errorf("reject the event of topic:%s, err:%s", sub.topic, err.Error())
+	}
+
+	task, ok, err := sub.generator.genTask(msg.Body, msg.Header)
 	if err != nil || !ok {
 		return err
 	}
 
-	d.messageChan <- message{
-		msg:  msg,
-		task: task,
+	eventId := sub.generator.eventId(msg.Body, msg.Header)
+	if d.isHandled(eventId, log) {
+		log.Debugf("drop the duplicate event(%s) of repo(%s)", eventId, task.String())
+
+		return nil
 	}
 
-	return nil
+	return d.repos.add(context.Background(), &message{
+		msg:     msg,
+		task:    task,
+		eventId: eventId,
+		topic:   sub.topic,
+	})
+}
+
+// isHandled checks whether the event has been handled. The event will
+// be handled if it can't be checked, since syncing again is harmless.
+// The failed event is not handled again when it is delivered repeatedly,
+// because it has been put to the retry queue.
+func (d *SyncRepo) isHandled(eventId string, log *logrus.Entry) bool {
+	if eventId == "" {
+		return false
+	}
+
+	ok, err := d.dedup.IsHandled(eventId)
+	if err != nil {
+		log.Errorf("check the event(%s) failed, err:%s", eventId, err.Error())
+
+		return false
+	}
+
+	return ok
+}
+
+// markHandled records the event and the ones collapsed into it after it
+// is handled successfully or put to the retry queue. The retried event
+// has been recorded at the first time.
+func (d *SyncRepo) markHandled(msg *message, log *logrus.Entry) {
+	if msg.retry != nil {
+		return
+	}
+
+	for _, id := range append([]string{msg.eventId}, msg.collapsed...) {
+		if id == "" {
+			continue
+		}
+
+		if err := d.dedup.MarkHandled(id); err != nil {
+			log.Errorf("record the event(%s) failed, err:%s", id, err.Error())
+		}
+	}
+}
+
+// generatorOf returns the generator of topic which the event is received
+// from. The generators of different topics may accept the same event, so
+// it is not looked up by the event. The retry tasks saved without topic
+// are handled by the first generator which accepts the event.
+func (d *SyncRepo) generatorOf(topic string, header map[string]string) (taskGenerator, error) {
+	for i := range d.subscriptions {
+		item := &d.subscriptions[i]
+
+		if topic == "" {
+			if item.generator.accept(header) {
+				return item.generator, nil
+			}
+
+			continue
+		}
+
+		if item.topic == topic {
+			return item.generator, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no generator of topic(%s) accepts the event", topic)
 }
 
 func (d *SyncRepo) validateMessage(msg *mq.Message) error {
@@ -121,56 +309,145 @@ func (d *SyncRepo) validateMessage(msg *mq.Message) error {
 	return nil
 }
 
+func (d *SyncRepo) pollRetryTasks(ctx context.Context, log *logrus.Entry) {
+	t := time.NewTicker(d.pollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-t.C:
+			d.dispatchRetryTasks(ctx, log)
+		}
+	}
+}
+
+func (d *SyncRepo) dispatchRetryTasks(ctx context.Context, log *logrus.Entry) {
+	tasks, err := d.retryer.claim(d.messageChanSize)
+	if err != nil {
+		log.Errorf("claim retry tasks failed, err:%s", err.Error())
+	}
+
+	for i := range tasks {
+		t := &tasks[i]
+		msg := &mq.Message{
+			Header: t.Header,
+			Body:   t.Payload,
+		}
+
+		task, ok, err := d.genRetryTask(t.Topic, msg)
+		if err != nil || !ok {
+			// it will never succeed, drop it.
+			log.Errorf("drop invalid retry task(%s), err:%v", t.Id, err)
+
+			if err := d.retryer.done(t); err != nil {
+				log.Errorf(
+					"delete retry task(%s) failed, err:%s", t.Id, err.Error(),
+				)
+			}
+
+			continue
+		}
+
+		err = d.repos.add(ctx, &message{msg: msg, task: task, topic: t.Topic, retry: t})
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *SyncRepo) genRetryTask(topic string, msg *mq.Message) (syncRepoTask, bool, error) {
+	g, err := d.generatorOf(topic, msg.Header)
+	if err != nil {
+		return syncRepoTask{}, false, err
+	}
+
+	return g.genTask(msg.Body, msg.Header)
+}
+
+func (d *SyncRepo) runTask(task *syncRepoTask) error {
+	switch task.kind {
+	case taskKindMove:
+		return d.syncservice.MoveRepo(&task.RepoInfo, task.from)
+
+	case taskKindDestroy:
+		return d.syncservice.DestroyRepo(&task.RepoInfo)
+
+	case taskKindTag:
+		return d.syncservice.SnapshotTag(&task.RepoInfo, task.tag)
+
+	case taskKindDeleteTag:
+		return d.syncservice.DeleteTag(&task.RepoInfo, task.tag)
+
+	default:
+		return d.syncservice.SyncRepo(&task.RepoInfo)
+	}
+}
+
 func (d *SyncRepo) doTask(log *logrus.Entry) {
-	f := func(msg message) (err error) {
+	f := func(msg *message) (err error) {
 		task := &msg.task
-		if err = d.syncservice.SyncRepo(task); err == nil {
+		if err = d.runTask(task); err == nil {
+			d.markHandled(msg, log)
+
+			if msg.retry != nil {
+				err = d.retryer.done(msg.retry)
+			}
+
+			return
+		}
+
+		s := task.String()
+		log.Errorf("%s repo(%s) failed, err:%s", task.kind, s, err.Error())
+
+		// the task triggered manually will not be retried.
+		if msg.msg == nil {
 			return nil
 		}
 
-		s := fmt.Sprintf(
-			"%s/%s/%s", task.Owner.Account(), task.RepoName, task.RepoId,
-		)
-		log.Errorf("sync repo(%s) failed, err:%s", s, err.Error())
+		t, err := d.retryer.fail(msg, err)
+		if err != nil {
+			return fmt.Errorf(
+				"record the failure of repo(%s) failed, err:%s",
+				s, err.Error(),
+			)
+		}
+
+		d.markHandled(msg, log)
+
+		if t.Quarantined {
+			metrics.IncRetry(metrics.RetryQuarantined)
 
-		if err = d.sendBack(msg.msg); err != nil {
 			log.Errorf(
-				"send back the message for repo(%s) failed, err:%s",
-				s, err.Error(),
+				"the event of repo(%s) is quarantined after %d attempts",
+				s, t.Attempts,
 			)
+		} else {
+			metrics.IncRetry(metrics.RetryScheduled)
 		}
 
 		return nil
 	}
 
-	for {
-		msg, ok := <-d.messageChan
-		if !ok {
-			return
+	for msg := range d.repos.ch {
+		// the repo is being handled by another worker which
+		// will handle this task after.
+		if !d.repos.start(msg) {
+			continue
 		}
 
-		if err := f(msg); err != nil {
-			log.Errorf("do task failed, err:%s", err.Error())
+		metrics.WorkerBusy()
+
+		// the pending tasks of repo are handled by the same worker
+		// right after, instead of being queued again.
+		for ok := true; ok; msg, ok = d.repos.done(msg) {
+			if err := f(msg); err != nil {
+				log.Errorf("do task failed, err:%s", err.Error())
+			}
 		}
-	}
-}
 
-func (d *SyncRepo) sendBack(e *mq.Message) error {
-	req, err := http.NewRequest(
-		http.MethodPost, d.endpoint, bytes.NewBuffer(e.Body),
-	)
-	if err != nil {
-		return err
+		metrics.WorkerIdle()
 	}
-
-	h := &req.Header
-	h.Add("Content-Type", "application/json")
-	h.Add("User-Agent", "xihe-sync-repo")
-	h.Add("X-Gitlab-Event", "System Hook")
-	h.Add("X-Gitlab-Token", d.hmac)
-	h.Add("X-Gitlab-Event-UUID", "73ed8438-1119-4bb8-ae9d-0180c88ef168")
-
-	_, err = d.hc.ForwardTo(req, nil)
-
-	return err
 }
@@ -0,0 +1,31 @@
+package syncrepo
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/infrastructure/dedupimpl"
+)
+
+func TestMarkHandledCollapsed(t *testing.T) {
+	d := &SyncRepo{
+		dedup: dedupimpl.NewMemoryEventDedup(3600, 10),
+	}
+
+	q := newRepoQueue(1, 10)
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := q.tryAdd(newTestMessage(t, "1", taskKindSync, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.markHandled(<-q.ch, logrus.NewEntry(logrus.New()))
+
+	// all the events are dropped if they are delivered again.
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if ok, err := d.dedup.IsHandled(id); err != nil || !ok {
+			t.Errorf("event %s: got (%v, %v), want it is handled", id, ok, err)
+		}
+	}
+}
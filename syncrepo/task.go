@@ -1,80 +1,148 @@
 package syncrepo
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
-	"github.com/xanzy/go-gitlab"
-
 	"github.com/opensourceways/xihe-sync-repo/app"
 	"github.com/opensourceways/xihe-sync-repo/domain"
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+const (
+	taskKindSync      = "sync"
+	taskKindMove      = "move"
+	taskKindDestroy   = "destroy"
+	taskKindTag       = "tag"
+	taskKindDeleteTag = "delete_tag"
 )
 
-type syncRepoTask = app.RepoInfo
+const (
+	tagRefPrefix    = "refs/tags/"
+	branchRefPrefix = "refs/heads/"
+)
 
-type syncRepoTaskGenerator struct {
-	userAgent string
+// isZeroCommit checks whether the commit is all zeros, which means
+// the ref is deleted.
+func isZeroCommit(commit string) bool {
+	return strings.Trim(commit, "0") == ""
 }
 
-func (d *syncRepoTaskGenerator) genTask(payload []byte, header map[string]string) (
-	cmd syncRepoTask, ok bool, err error,
-) {
-	eventType, err := d.parseRequest(payload, header)
-	if err != nil {
-		err = fmt.Errorf("invalid task, err:%s", err.Error())
+type syncRepoTask struct {
+	app.RepoInfo
 
-		return
-	}
+	kind string
 
-	if gitlab.EventType(eventType) != gitlab.EventTypePush {
-		return
-	}
+	// from is the old owner of repo which is moved.
+	from domain.Account
 
-	e := new(gitlab.PushEvent)
-	if err = json.Unmarshal(payload, e); err != nil {
-		return
+	tag string
+}
+
+func newSyncTask(info *app.RepoInfo) syncRepoTask {
+	return syncRepoTask{
+		RepoInfo: *info,
+		kind:     taskKindSync,
 	}
+}
+
+// repoKey identifies the repo, same as the key of its sync lock.
+func (t *syncRepoTask) repoKey() string {
+	return t.RepoKey()
+}
+
+func (t *syncRepoTask) String() string {
+	return fmt.Sprintf(
+		"%s/%s/%s", t.Owner.Account(), t.RepoName, t.RepoId,
+	)
+}
+
+// pushEvent is the push event of github, which gitea and gitee are
+// compatible with. It is delivered for both the branches and tags.
+type pushEvent struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+
+	Repository struct {
+		Id            int    `json:"id"`
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+func (e *pushEvent) isDeleted() bool {
+	return e.Deleted || isZeroCommit(e.After)
+}
 
-	v := strings.Split(e.Project.PathWithNamespace, "/")
+func (e *pushEvent) genTask() (cmd syncRepoTask, ok bool, err error) {
+	if strings.HasPrefix(e.Ref, tagRefPrefix) {
+		cmd.tag = strings.TrimPrefix(e.Ref, tagRefPrefix)
+
+		if e.isDeleted() {
+			cmd.kind = taskKindDeleteTag
+		} else {
+			cmd.kind = taskKindTag
+		}
+	} else {
+		// there is nothing to sync when the branch is deleted.
+		if e.isDeleted() {
+			return
+		}
+
+		if cmd.Branch = strings.TrimPrefix(e.Ref, branchRefPrefix); cmd.Branch == e.Ref {
+			err = fmt.Errorf("invalid ref:%s", e.Ref)
+
+			return
+		}
+
+		cmd.DefaultBranch = e.Repository.DefaultBranch
+		cmd.kind = taskKindSync
+	}
 
-	if cmd.Owner, err = domain.NewAccount(v[0]); err != nil {
+	if err = cmd.setRepo(e.Repository.FullName, e.Repository.Id); err != nil {
 		return
 	}
-	cmd.RepoName = v[1]
-	cmd.RepoId = strconv.Itoa(e.ProjectID)
 
 	ok = true
 
 	return
 }
 
-func (d *syncRepoTaskGenerator) parseRequest(payload []byte, header map[string]string) (
-	eventType string, err error,
-) {
-	if header == nil {
-		err = errors.New("no header")
+// taskGenerator generates the task from the event of a platform.
+type taskGenerator interface {
+	// accept checks whether the event is from the platform.
+	accept(header map[string]string) bool
 
-		return
-	}
+	// eventId returns the unique id of event.
+	eventId(payload []byte, header map[string]string) string
 
-	if header["User-Agent"] != d.userAgent {
-		err = errors.New("unknown User-Agent")
+	genTask(payload []byte, header map[string]string) (syncRepoTask, bool, error)
+}
 
-		return
-	}
+var generatorBuilders = map[string]func(cfg *TopicConfig) taskGenerator{
+	platform.Gitlab: newGitlabTaskGenerator,
+	platform.Github: newGithubTaskGenerator,
+	platform.Gitea:  newGiteaTaskGenerator,
+	platform.Gitee:  newGiteeTaskGenerator,
+}
 
-	if eventType = header["X-Gitlab-Event"]; eventType == "" {
-		err = errors.New("missing X-Gitlab-Event")
+func newTaskGenerator(cfg *TopicConfig) taskGenerator {
+	return generatorBuilders[cfg.Platform](cfg)
+}
 
-		return
+func (t *syncRepoTask) setRepo(pathWithNamespace string, projectId int) (err error) {
+	v := strings.Split(pathWithNamespace, "/")
+	if len(v) != 2 {
+		return fmt.Errorf("invalid path_with_namespace:%s", pathWithNamespace)
 	}
 
-	if header["X-Gitlab-Event-UUID"] == "" {
-		err = errors.New("missing X-Gitlab-Event-UUID")
+	if t.Owner, err = domain.NewAccount(v[0]); err != nil {
+		return
 	}
+	t.RepoName = v[1]
+	t.RepoId = strconv.Itoa(projectId)
 
 	return
 }
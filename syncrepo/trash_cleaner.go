@@ -0,0 +1,62 @@
+package syncrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opensourceways/xihe-sync-repo/app"
+	"github.com/opensourceways/xihe-sync-repo/domain/election"
+)
+
+const trashCleanerLeaderName = "xihe-sync-repo-trash-cleaner"
+
+// TrashCleaner cleans the expired files of destroyed repos periodically.
+// Only the leader of instances does it.
+type TrashCleaner struct {
+	interval int
+	holder   string
+	service  app.SyncService
+	elector  election.Elector
+}
+
+func NewTrashCleaner(
+	cfg *Config, holder string,
+	service app.SyncService, elector election.Elector,
+) *TrashCleaner {
+	return &TrashCleaner{
+		interval: cfg.TrashCleanInterval,
+		holder:   holder,
+		service:  service,
+		elector:  elector,
+	}
+}
+
+func (c *TrashCleaner) Run(ctx context.Context, log *logrus.Entry) {
+	t := time.NewTicker(time.Duration(c.interval) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-t.C:
+			ok, err := c.elector.TryAcquire(
+				trashCleanerLeaderName, c.holder, int64(2*c.interval),
+			)
+			if err != nil {
+				log.Errorf("elect trash cleaner leader failed, err:%s", err.Error())
+			}
+
+			if !ok {
+				continue
+			}
+
+			if err := c.service.CleanTrash(); err != nil {
+				log.Errorf("clean trash failed, err:%s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package syncrepo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+	"github.com/opensourceways/xihe-sync-repo/metrics"
+)
+
+const (
+	// VerifyModeToken compares the header with the secret.
+	VerifyModeToken = "token"
+
+	// VerifyModeHMAC compares the header with the hex of
+	// HMAC-SHA256(secret, payload) which may be prefixed by sha256=
+	VerifyModeHMAC = "hmac"
+
+	hmacPrefix = "sha256="
+)
+
+type verifyDefault struct {
+	mode   string
+	header string
+}
+
+// verifyDefaults are the ways each platform signs its events.
+var verifyDefaults = map[string]verifyDefault{
+	platform.Gitlab: {VerifyModeToken, "X-Gitlab-Token"},
+	platform.Github: {VerifyModeHMAC, "X-Hub-Signature-256"},
+	platform.Gitea:  {VerifyModeHMAC, "X-Gitea-Signature"},
+	platform.Gitee:  {VerifyModeToken, "X-Gitee-Token"},
+}
+
+type VerifyConfig struct {
+	// Secrets are used to verify the events. The event is accepted if it
+	// matches any of them, so the secret can be rotated by adding the new
+	// one before the producers switch to it, and removing the old one after.
+	// It is required unless Disabled is true.
+	Secrets []string `json:"secrets"`
+
+	// Disabled turns off the verification, so that anyone who can produce
+	// to the topic can trigger the syncs. A warning is logged at startup.
+	Disabled bool `json:"disabled"`
+
+	// Mode can be token or hmac. It is the way of platform by default,
+	// which is token for gitlab and gitee, and hmac for github and gitea.
+	Mode string `json:"mode"`
+
+	// Header carries the token or signature. It is the header of
+	// platform by default if Mode is the default one too.
+	Header string `json:"header"`
+}
+
+func (cfg *VerifyConfig) setDefault(p string) {
+	v := verifyDefaults[p]
+
+	if cfg.Mode == "" {
+		cfg.Mode = v.mode
+	}
+
+	if cfg.Header == "" && cfg.Mode == v.mode {
+		cfg.Header = v.header
+	}
+}
+
+func (cfg *VerifyConfig) validate() error {
+	if cfg.Disabled {
+		return nil
+	}
+
+	if len(cfg.Secrets) == 0 {
+		return errors.New("missing verify secrets, set verify.disabled to skip the verification")
+	}
+
+	if cfg.Mode != VerifyModeToken && cfg.Mode != VerifyModeHMAC {
+		return fmt.Errorf("invalid verify mode:%s", cfg.Mode)
+	}
+
+	if cfg.Header == "" {
+		return errors.New("missing verify header")
+	}
+
+	for _, item := range cfg.Secrets {
+		if item == "" {
+			return errors.New("empty verify secret")
+		}
+	}
+
+	return nil
+}
+
+type eventVerifier struct {
+	cfg   VerifyConfig
+	topic string
+}
+
+func newEventVerifier(cfg *TopicConfig) eventVerifier {
+	return eventVerifier{
+		cfg:   cfg.Verify,
+		topic: cfg.Topic,
+	}
+}
+
+// verify checks the event and removes the token from header after that,
+// so that it will not be saved together with the event to be retried.
+func (v *eventVerifier) verify(payload []byte, header map[string]string) error {
+	if v.cfg.Disabled {
+		return nil
+	}
+
+	s := header[v.cfg.Header]
+	if s == "" {
+		metrics.IncRejectedEvent(v.topic, metrics.RejectMissingSignature)
+
+		return errors.New("missing " + v.cfg.Header)
+	}
+
+	for _, secret := range v.cfg.Secrets {
+		if v.match(secret, s, payload) {
+			if v.cfg.Mode == VerifyModeToken {
+				delete(header, v.cfg.Header)
+			}
+
+			return nil
+		}
+	}
+
+	metrics.IncRejectedEvent(v.topic, metrics.RejectInvalidSignature)
+
+	return errors.New("invalid " + v.cfg.Header)
+}
+
+func (v *eventVerifier) match(secret, s string, payload []byte) bool {
+	if v.cfg.Mode == VerifyModeToken {
+		return subtle.ConstantTimeCompare([]byte(secret), []byte(s)) == 1
+	}
+
+	sig, err := hex.DecodeString(strings.TrimPrefix(s, hmacPrefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hmac.Equal(sig, mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package syncrepo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/opensourceways/xihe-sync-repo/domain/platform"
+)
+
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestEventVerifier(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/main"}`)
+
+	cases := []struct {
+		name     string
+		platform string
+		cfg      VerifyConfig
+		header   map[string]string
+		wantErr  bool
+		// wantKept is whether the header is kept after the verification.
+		wantKept bool
+	}{
+		{
+			name:     "token",
+			platform: platform.Gitlab,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Gitlab-Token": "s1"},
+		},
+		{
+			name:     "rotated token",
+			platform: platform.Gitee,
+			cfg:      VerifyConfig{Secrets: []string{"s1", "s2"}},
+			header:   map[string]string{"X-Gitee-Token": "s2"},
+		},
+		{
+			name:     "invalid token",
+			platform: platform.Gitlab,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Gitlab-Token": "s2"},
+			wantErr:  true,
+			wantKept: true,
+		},
+		{
+			name:     "missing token",
+			platform: platform.Gitlab,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Gitee-Token": "s1"},
+			wantErr:  true,
+		},
+		{
+			name:     "hmac",
+			platform: platform.Gitea,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Gitea-Signature": sign("s1", payload)},
+			wantKept: true,
+		},
+		{
+			name:     "hmac with prefix",
+			platform: platform.Github,
+			cfg:      VerifyConfig{Secrets: []string{"s1", "s2"}},
+			header:   map[string]string{"X-Hub-Signature-256": hmacPrefix + sign("s2", payload)},
+			wantKept: true,
+		},
+		{
+			name:     "invalid hmac",
+			platform: platform.Github,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Hub-Signature-256": hmacPrefix + sign("s2", payload)},
+			wantErr:  true,
+			wantKept: true,
+		},
+		{
+			name:     "hmac which is not hex",
+			platform: platform.Gitea,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}},
+			header:   map[string]string{"X-Gitea-Signature": "s1"},
+			wantErr:  true,
+			wantKept: true,
+		},
+		{
+			name:     "custom header",
+			platform: platform.Gitlab,
+			cfg:      VerifyConfig{Secrets: []string{"s1"}, Mode: VerifyModeHMAC, Header: "X-Signature"},
+			header:   map[string]string{"X-Signature": sign("s1", payload)},
+			wantKept: true,
+		},
+		{
+			name:     "disabled",
+			platform: platform.Gitlab,
+			cfg:      VerifyConfig{Disabled: true},
+			header:   map[string]string{"X-Gitlab-Token": "s2"},
+			wantKept: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := TopicConfig{Topic: "topic", Platform: c.platform, Verify: c.cfg}
+			cfg.Verify.setDefault(cfg.Platform)
+
+			if err := cfg.Verify.validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			v := newEventVerifier(&cfg)
+
+			n := len(c.header)
+			err := v.verify(payload, c.header)
+
+			if (err != nil) != c.wantErr {
+				t.Errorf("got %v, want error: %t", err, c.wantErr)
+			}
+
+			if c.wantErr && len(c.header) != n {
+				t.Error("the header is changed after failing")
+			}
+
+			if !c.wantErr && (len(c.header) == n) != c.wantKept {
+				t.Errorf("got header %v, want it kept: %t", c.header, c.wantKept)
+			}
+		})
+	}
+}
+
+func TestVerifyConfigValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     VerifyConfig
+		wantErr bool
+	}{
+		{name: "missing secrets", cfg: VerifyConfig{}, wantErr: true},
+		{name: "empty secret", cfg: VerifyConfig{Secrets: []string{"s1", ""}}, wantErr: true},
+		{name: "invalid mode", cfg: VerifyConfig{Secrets: []string{"s1"}, Mode: "md5"}, wantErr: true},
+		{
+			name:    "missing header of custom mode",
+			cfg:     VerifyConfig{Secrets: []string{"s1"}, Mode: VerifyModeHMAC},
+			wantErr: true,
+		},
+		{name: "disabled", cfg: VerifyConfig{Disabled: true}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := c.cfg
+			cfg.setDefault(platform.Gitlab)
+
+			if err := cfg.validate(); (err != nil) != c.wantErr {
+				t.Errorf("got %v, want error: %t", err, c.wantErr)
+			}
+		})
+	}
+}
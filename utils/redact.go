@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "***"
+
+// reURLCredential matches the userinfo of url, such as https://user:token@
+var reURLCredential = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+
+var secrets = struct {
+	sync.RWMutex
+
+	values   map[string]bool
+	replacer *strings.Replacer
+}{
+	values:   map[string]bool{},
+	replacer: strings.NewReplacer(),
+}
+
+// AddSecrets registers the secrets which will be redacted by Redact.
+func AddSecrets(v ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	for _, item := range v {
+		if item != "" {
+			secrets.values[item] = true
+		}
+	}
+
+	values := make([]string, 0, len(secrets.values))
+	for item := range secrets.values {
+		values = append(values, item)
+	}
+
+	// replace the longer one first in case a secret includes another.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, item := range values {
+		pairs = append(pairs, item, redacted)
+	}
+
+	secrets.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces the registered secrets and the credentials of urls in s.
+func Redact(s string) string {
+	secrets.RLock()
+	r := secrets.replacer
+	secrets.RUnlock()
+
+	return reURLCredential.ReplaceAllString(r.Replace(s), "${1}"+redacted+"@")
+}
+
+// RedactFormatter redacts the logs formatted by Formatter, including
+// the message and the fields.
+type RedactFormatter struct {
+	logrus.Formatter
+}
+
+func (f *RedactFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	b, err := f.Formatter.Format(e)
+	if err != nil {
+		return b, err
+	}
+
+	return []byte(Redact(string(b))), nil
+}